@@ -3,9 +3,11 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -15,14 +17,32 @@ import (
 
 const defaultBaseURL = "https://api.prd-1.iq.live.umoiq.com"
 
+// predictionsRateShare is the fraction of the rate limit predictions may use
+const predictionsRateShare = 0.75
+
 // MuniClient is the interface for interacting with the MUNI API
 type MuniClient interface {
 	GetAllRoutes(ctx context.Context) ([]muni.RouteInfo, error)
 	GetRouteDetails(ctx context.Context, routeID string) (*muni.RouteDetails, error)
 	GetPredictions(ctx context.Context, routeID, stopID string) ([]muni.Prediction, error)
+	GetStopPredictions(ctx context.Context, stopID string) ([]muni.Prediction, error)
+	GetFilteredPredictions(ctx context.Context, routeID, stopID string, filter muni.PredictionFilter) ([]muni.Prediction, error)
+	GetPredictionsBatch(ctx context.Context, requests []muni.PredictionRequest) ([]muni.PredictionResult, error)
+	GetVehicleLocations(ctx context.Context, routeID string) ([]muni.VehicleLocation, error)
+	GetAlerts(ctx context.Context, filter muni.AlertFilter) ([]muni.Alert, error)
+	SearchStops(ctx context.Context, query string, limit int) ([]muni.StopMatch, error)
+	FindNearbyStops(ctx context.Context, lat, lon, radiusMeters float64, limit int) ([]muni.NearbyStop, error)
+	ResolveStopCode(ctx context.Context, code string) (*muni.StopInfo, error)
+	ResolveRoute(ctx context.Context, name string) (*muni.RouteInfo, error)
+	GetStopDetails(ctx context.Context, stopID string) (*muni.StopInfo, error)
+	GetRouteStopSequence(ctx context.Context, routeID, directionID string) (*muni.RouteStopSequence, error)
+	GetStopsBetween(ctx context.Context, routeID, fromStopID, toStopID string) (*muni.StopsBetween, error)
+	FindDirectRoutes(ctx context.Context, fromStopID, toStopID string) ([]muni.DirectRoute, error)
 	ClearCache()
+	InvalidateCache(filter muni.CacheFilter) int
 	EnableCache()
 	DisableCache()
+	CacheStats() muni.CacheStats
 }
 
 var muniClient MuniClient
@@ -33,8 +53,15 @@ func main() {
 		baseURL = defaultBaseURL
 	}
 
-	cacheTTL := 5 * time.Minute // Default cache TTL
-	muniClient = muni.NewClient(baseURL, muni.WithCacheTTL(cacheTTL))
+	opts, err := clientOptionsFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	client := muni.NewClient(baseURL, opts...)
+	defer client.Close()
+	muniClient = client
 
 	// Create MCP server
 	s := server.NewMCPServer(
@@ -45,11 +72,13 @@ func main() {
 	// Add a simple health check tool
 	healthTool := mcp.NewTool("health_check",
 		mcp.WithDescription("Check if the MUNI API server is healthy"),
+		withAgencyID(),
 	)
 
 	// Add route listing tool
 	allRoutesTool := mcp.NewTool("list_all_routes",
 		mcp.WithDescription("Get a list of all MUNI routes with detailed information"),
+		withAgencyID(),
 	)
 
 	// Add route details tool
@@ -57,26 +86,206 @@ func main() {
 		mcp.WithDescription("Get detailed information about a specific MUNI route"),
 		mcp.WithString("route_id",
 			mcp.Required(),
-			mcp.Description("ID of the route (e.g., 'N' for N-Judah)"),
+			mcp.Description("ID or name of the route (e.g., 'N', 'N-Judah' or '38 Rapid')"),
 		),
+		withAgencyID(),
 	)
 
 	// Add predictions tool
 	predictionsTool := mcp.NewTool("get_predictions",
-		mcp.WithDescription("Get real-time arrival/departure predictions for a specific stop on a route"),
+		mcp.WithDescription("Get real-time arrival/departure predictions for a specific stop on a route, including crowding (occupancy), delay and train length"),
+		mcp.WithString("route_id",
+			mcp.Required(),
+			mcp.Description("ID or name of the route (e.g., 'N', 'N-Judah' or '38 Rapid')"),
+		),
+		mcp.WithString("stop_id",
+			mcp.Description("ID of the stop (e.g., '7142'). Required unless stop_code is given"),
+		),
+		withStopCode(),
+		mcp.WithString("direction",
+			mcp.Description("Only return vehicles heading this way: a direction ID, 'Inbound'/'Outbound', or part of the destination (e.g., 'Embarcadero')"),
+		),
+		mcp.WithNumber("max_minutes",
+			mcp.Description("Only return vehicles arriving within this many minutes"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of predictions to return"),
+		),
+		withAgencyID(),
+	)
+
+	// Add all-routes stop predictions tool
+	stopPredictionsTool := mcp.NewTool("get_stop_predictions",
+		mcp.WithDescription("Get real-time arrival/departure predictions for every route serving a stop, merged and sorted by arrival time"),
+		mcp.WithString("stop_id",
+			mcp.Description("ID of the stop (e.g., '7142'). Required unless stop_code is given"),
+		),
+		withStopCode(),
+		withAgencyID(),
+	)
+
+	// Add batch predictions tool
+	predictionsBatchTool := mcp.NewTool("get_predictions_batch",
+		mcp.WithDescription("Get real-time predictions for many route/stop pairs in one call. Each pair gets its own result or error"),
+		mcp.WithArray("requests",
+			mcp.Required(),
+			mcp.Description("Route/stop pairs to fetch predictions for (at most 50)"),
+			mcp.Items(map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"route_id": map[string]interface{}{
+						"type":        "string",
+						"description": "ID or name of the route (e.g., 'N', 'N-Judah' or '38 Rapid')",
+					},
+					"stop_id": map[string]interface{}{
+						"type":        "string",
+						"description": "ID of the stop (e.g., '7142'). Required unless stop_code is given",
+					},
+					"stop_code": map[string]interface{}{
+						"type":        "string",
+						"description": "Public stop code shown on the stop sign (e.g., '13860'), instead of stop_id",
+					},
+				},
+				"required": []string{"route_id"},
+			}),
+		),
+		withAgencyID(),
+	)
+
+	// Add vehicle locations tool
+	vehicleLocationsTool := mcp.NewTool("get_vehicle_locations",
+		mcp.WithDescription("Get the last reported position, heading and speed of every vehicle on a route"),
 		mcp.WithString("route_id",
 			mcp.Required(),
-			mcp.Description("ID of the route (e.g., 'N' for N-Judah)"),
+			mcp.Description("ID or name of the route (e.g., 'N', 'N-Judah' or '38 Rapid')"),
+		),
+		withAgencyID(),
+	)
+
+	// Add service alerts tool
+	serviceAlertsTool := mcp.NewTool("get_service_alerts",
+		mcp.WithDescription("Get rider alerts currently in effect, such as detours, elevator outages and line shutdowns"),
+		mcp.WithString("route_id",
+			mcp.Description("Only return alerts affecting this route, by ID or name (e.g., 'N' or 'N-Judah')"),
 		),
 		mcp.WithString("stop_id",
+			mcp.Description("Only return alerts affecting this stop (e.g., '7142')"),
+		),
+		withStopCode(),
+		withAgencyID(),
+	)
+
+	// Add stop search tool
+	searchStopsTool := mcp.NewTool("search_stops",
+		mcp.WithDescription("Find stops by name across the whole system, e.g. 'church and duboce'. Returns stop IDs and codes with the routes and directions serving each stop"),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("Stop name or cross streets to search for"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of stops to return (default 10, at most 50)"),
+		),
+		withAgencyID(),
+	)
+
+	// Add nearby stops tool
+	nearbyStopsTool := mcp.NewTool("find_nearby_stops",
+		mcp.WithDescription("Find the stops closest to a location, sorted by distance, with walking time estimates and the routes serving each stop"),
+		mcp.WithNumber("lat",
 			mcp.Required(),
-			mcp.Description("ID of the stop (e.g., '7142')"),
+			mcp.Description("Latitude of the location (e.g., 37.7693)"),
 		),
+		mcp.WithNumber("lon",
+			mcp.Required(),
+			mcp.Description("Longitude of the location (e.g., -122.4290)"),
+		),
+		mcp.WithNumber("radius_meters",
+			mcp.Description("Search radius in meters (default 500, at most 5000)"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of stops to return (default 10, at most 50)"),
+		),
+		withAgencyID(),
+	)
+
+	// Add stop details tool
+	stopDetailsTool := mcp.NewTool("get_stop_details",
+		mcp.WithDescription("Get a stop's name, code and coordinates along with every route serving it, the directions that stop there, and the routes' colors"),
+		mcp.WithString("stop_id",
+			mcp.Description("ID of the stop (e.g., '7142'). Required unless stop_code is given"),
+		),
+		withStopCode(),
+		withAgencyID(),
+	)
+
+	// Add route stops tool
+	routeStopsTool := mcp.NewTool("get_route_stops",
+		mcp.WithDescription("Get the stops a route makes, in order, for each direction, with the terminals flagged and the distance along the route to each stop"),
+		mcp.WithString("route_id",
+			mcp.Required(),
+			mcp.Description("ID or name of the route (e.g., 'N', 'N-Judah' or '38 Rapid')"),
+		),
+		mcp.WithString("direction",
+			mcp.Description("Only return this direction: a direction ID, 'Inbound'/'Outbound', or part of the direction's name. Defaults to every direction"),
+		),
+		withAgencyID(),
+	)
+
+	// Add stops between tool
+	stopsBetweenTool := mcp.NewTool("get_stops_between",
+		mcp.WithDescription("Count the stops between two stops on a route, e.g. 'how many stops from Castro to Embarcadero on the K?'. Works out the direction of travel and returns the stops passed in order with the distance along the route"),
+		mcp.WithString("route_id",
+			mcp.Required(),
+			mcp.Description("ID or name of the route (e.g., 'N', 'N-Judah' or '38 Rapid')"),
+		),
+		mcp.WithString("from_stop_id",
+			mcp.Description("ID of the stop to board at. Required unless from_stop_code is given"),
+		),
+		mcp.WithString("from_stop_code",
+			mcp.Description("Public stop code of the stop to board at, instead of from_stop_id"),
+		),
+		mcp.WithString("to_stop_id",
+			mcp.Description("ID of the stop to get off at. Required unless to_stop_code is given"),
+		),
+		mcp.WithString("to_stop_code",
+			mcp.Description("Public stop code of the stop to get off at, instead of to_stop_id"),
+		),
+		withAgencyID(),
+	)
+
+	// Add direct routes tool
+	directRoutesTool := mcp.NewTool("find_direct_routes",
+		mcp.WithDescription("Find every route that goes directly from one stop to another without a transfer, with the next departures in the right direction, soonest first"),
+		mcp.WithString("from_stop_id",
+			mcp.Description("ID of the stop to board at. Required unless from_stop_code is given"),
+		),
+		mcp.WithString("from_stop_code",
+			mcp.Description("Public stop code of the stop to board at, instead of from_stop_id"),
+		),
+		mcp.WithString("to_stop_id",
+			mcp.Description("ID of the stop to get off at. Required unless to_stop_code is given"),
+		),
+		mcp.WithString("to_stop_code",
+			mcp.Description("Public stop code of the stop to get off at, instead of to_stop_id"),
+		),
+		withAgencyID(),
 	)
 
 	// Add cache management tools
 	clearCacheTool := mcp.NewTool("clear_cache",
-		mcp.WithDescription("Clear the cached MUNI API responses"),
+		mcp.WithDescription("Clear the cached MUNI API responses. With no arguments everything is cleared; otherwise only entries matching all given arguments are removed"),
+		mcp.WithString("route_id",
+			mcp.Description("Only clear route details, predictions and vehicle locations for this route, by ID or name (e.g., 'N' or 'N-Judah')"),
+		),
+		mcp.WithString("kind",
+			mcp.Description("Only clear one kind of data: 'all_routes', 'route_details', 'predictions', 'vehicles' or 'alerts'"),
+		),
+		mcp.WithString("prefix",
+			mcp.Description("Only clear cache keys starting with this prefix (e.g., 'route_details:sfmta-cis:N')"),
+		),
+		mcp.WithString("agency_id",
+			mcp.Description("Only clear entries for this transit agency (e.g., 'sfmta-cis')"),
+		),
 	)
 
 	toggleCacheTool := mcp.NewTool("toggle_cache",
@@ -87,13 +296,28 @@ func main() {
 		),
 	)
 
+	inspectCacheTool := mcp.NewTool("inspect_cache",
+		mcp.WithDescription("Show cache statistics: hit ratios, evictions and the age, expiry and size of every cached key"),
+	)
+
 	// Add tool handlers
 	s.AddTool(healthTool, healthCheckHandler)
 	s.AddTool(allRoutesTool, listAllRoutesHandler)
 	s.AddTool(routeDetailsTool, getRouteDetailsHandler)
 	s.AddTool(predictionsTool, getPredictionsHandler)
+	s.AddTool(stopPredictionsTool, getStopPredictionsHandler)
+	s.AddTool(predictionsBatchTool, getPredictionsBatchHandler)
+	s.AddTool(vehicleLocationsTool, getVehicleLocationsHandler)
+	s.AddTool(serviceAlertsTool, getServiceAlertsHandler)
+	s.AddTool(searchStopsTool, searchStopsHandler)
+	s.AddTool(nearbyStopsTool, findNearbyStopsHandler)
+	s.AddTool(stopDetailsTool, getStopDetailsHandler)
+	s.AddTool(routeStopsTool, getRouteStopsHandler)
+	s.AddTool(stopsBetweenTool, getStopsBetweenHandler)
+	s.AddTool(directRoutesTool, findDirectRoutesHandler)
 	s.AddTool(clearCacheTool, clearCacheHandler)
 	s.AddTool(toggleCacheTool, toggleCacheHandler)
+	s.AddTool(inspectCacheTool, inspectCacheHandler)
 
 	// Start the stdio server
 	log.Println("Starting SF MUNI MCP server...")
@@ -103,6 +327,67 @@ func main() {
 	}
 }
 
+// clientOptionsFromEnv builds the MUNI client options from environment variables
+func clientOptionsFromEnv() ([]muni.ClientOption, error) {
+	cacheTTL := 5 * time.Minute // Default cache TTL
+	opts := []muni.ClientOption{muni.WithCacheTTL(cacheTTL)}
+
+	if agencyID := os.Getenv("MUNI_AGENCY"); agencyID != "" {
+		opts = append(opts, muni.WithAgency(agencyID))
+	}
+
+	if dir := os.Getenv("MUNI_CACHE_DIR"); dir != "" {
+		opts = append(opts, muni.WithCacheDir(dir))
+	}
+
+	if value := os.Getenv("MUNI_PREDICTION_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("MUNI_PREDICTION_TTL must be a duration such as 10s, got %q", value)
+		}
+		opts = append(opts, muni.WithPredictionTTL(ttl))
+	}
+
+	if value := os.Getenv("MUNI_RATE_LIMIT"); value != "" {
+		rps, err := strconv.ParseFloat(value, 64)
+		if err != nil || rps <= 0 {
+			return nil, fmt.Errorf("MUNI_RATE_LIMIT must be a positive number of requests per second, got %q", value)
+		}
+
+		limit := muni.RateLimit{RequestsPerSecond: rps}
+
+		if value := os.Getenv("MUNI_RATE_LIMIT_BURST"); value != "" {
+			burst, err := strconv.Atoi(value)
+			if err != nil || burst < 1 {
+				return nil, fmt.Errorf("MUNI_RATE_LIMIT_BURST must be a positive integer, got %q", value)
+			}
+			limit.Burst = burst
+		}
+
+		if value := os.Getenv("MUNI_RATE_LIMIT_REJECT"); value != "" {
+			reject, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("MUNI_RATE_LIMIT_REJECT must be a boolean, got %q", value)
+			}
+			limit.Reject = reject
+		}
+
+		// Cap predictions below the total budget so they can't starve route metadata
+		predictionsLimit := limit
+		predictionsLimit.RequestsPerSecond = rps * predictionsRateShare
+		if predictionsLimit.Burst > 1 {
+			predictionsLimit.Burst = int(float64(predictionsLimit.Burst) * predictionsRateShare)
+		}
+
+		opts = append(opts,
+			muni.WithRateLimit(limit),
+			muni.WithEndpointRateLimit(muni.EndpointPredictions, predictionsLimit),
+		)
+	}
+
+	return opts, nil
+}
+
 // Create a helper function for JSON content
 func newJSONToolResult(data interface{}) (*mcp.CallToolResult, error) {
 	jsonData, err := json.Marshal(data)
@@ -119,17 +404,200 @@ func newJSONToolResult(data interface{}) (*mcp.CallToolResult, error) {
 	}, nil
 }
 
+// activeAlerts returns the alerts matching filter. Alerts only add context to
+// other results, so failures are logged rather than returned.
+func activeAlerts(ctx context.Context, filter muni.AlertFilter) []muni.Alert {
+	alerts, err := muniClient.GetAlerts(ctx, filter)
+	if err != nil {
+		log.Printf("Error fetching service alerts: %v", err)
+		return nil
+	}
+	return alerts
+}
+
+// withAlerts appends alerts to a tool result as a second JSON content item,
+// leaving the result unchanged when there are none
+func withAlerts(result *mcp.CallToolResult, alerts []muni.Alert) (*mcp.CallToolResult, error) {
+	if len(alerts) == 0 {
+		return result, nil
+	}
+
+	jsonData, err := json.Marshal(map[string][]muni.Alert{"alerts": alerts})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to marshal to JSON: %v", err)), nil
+	}
+
+	result.Content = append(result.Content, mcp.NewTextContent(string(jsonData)))
+	return result, nil
+}
+
+// withAgencyID adds the optional agency_id argument to a tool
+func withAgencyID() mcp.ToolOption {
+	return mcp.WithString("agency_id",
+		mcp.Description("ID of the transit agency on the umoiq platform (defaults to SF MUNI, 'sfmta-cis')"),
+	)
+}
+
+// resolveRouteID resolves a route name, as a rider would say it, to a route ID.
+// If the route list can't be fetched the name is used as given.
+func resolveRouteID(ctx context.Context, name string) (string, error) {
+	route, err := muniClient.ResolveRoute(ctx, name)
+	if err == nil {
+		return route.ID, nil
+	}
+
+	if errors.Is(err, muni.ErrRouteNotFound) || errors.Is(err, muni.ErrRouteIDRequired) {
+		return "", err
+	}
+
+	log.Printf("Error resolving route %s: %v", name, err)
+	return name, nil
+}
+
+// withStopCode adds the optional stop_code argument to a tool, an alternative to stop_id
+func withStopCode() mcp.ToolOption {
+	return mcp.WithString("stop_code",
+		mcp.Description("Public stop code shown on the stop sign (e.g., '13860'), instead of stop_id"),
+	)
+}
+
+// stopIDArgument returns the stop_id argument, or the ID of the stop with the
+// given stop_code. It returns an empty ID if neither is given.
+func stopIDArgument(ctx context.Context, args map[string]interface{}) (string, error) {
+	return namedStopIDArgument(ctx, args, "stop")
+}
+
+// requiredStopID is stopIDArgument for tools that need a stop
+func requiredStopID(ctx context.Context, args map[string]interface{}) (string, error) {
+	return requiredNamedStopID(ctx, args, "stop")
+}
+
+// namedStopIDArgument is stopIDArgument for the <name>_id and <name>_code
+// arguments, for tools that take more than one stop
+func namedStopIDArgument(ctx context.Context, args map[string]interface{}, name string) (string, error) {
+	stopID, hasID := args[name+"_id"]
+	stopCode, hasCode := args[name+"_code"]
+
+	if hasID && stopID != nil && hasCode && stopCode != nil {
+		return "", fmt.Errorf("give either %s_id or %s_code, not both", name, name)
+	}
+
+	if hasCode && stopCode != nil {
+		code, ok := stopCode.(string)
+		if !ok {
+			return "", fmt.Errorf("%s_code must be a string", name)
+		}
+
+		stop, err := muniClient.ResolveStopCode(ctx, code)
+		if err != nil {
+			return "", err
+		}
+
+		return stop.ID, nil
+	}
+
+	if !hasID || stopID == nil {
+		return "", nil
+	}
+
+	id, ok := stopID.(string)
+	if !ok {
+		return "", fmt.Errorf("%s_id must be a string", name)
+	}
+
+	return id, nil
+}
+
+// requiredNamedStopID is namedStopIDArgument for tools that need the stop
+func requiredNamedStopID(ctx context.Context, args map[string]interface{}, name string) (string, error) {
+	stopID, err := namedStopIDArgument(ctx, args, name)
+	if err == nil && stopID == "" {
+		err = fmt.Errorf("%s_id or %s_code is required", name, name)
+	}
+	return stopID, err
+}
+
+// agencyContext applies the optional agency_id argument to the request context
+func agencyContext(ctx context.Context, request mcp.CallToolRequest) (context.Context, error) {
+	agencyID, err := optionalString(request, "agency_id")
+	if err != nil {
+		return nil, err
+	}
+
+	return muni.ContextWithAgency(ctx, agencyID), nil
+}
+
+// optionalString returns the named string argument, or an empty string if it wasn't given
+func optionalString(request mcp.CallToolRequest, name string) (string, error) {
+	value, exists := request.Params.Arguments[name]
+	if !exists || value == nil {
+		return "", nil
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", name)
+	}
+
+	return str, nil
+}
+
+// optionalFloat returns the named number argument, or zero if it wasn't given
+func optionalFloat(request mcp.CallToolRequest, name string) (float64, error) {
+	value, exists := request.Params.Arguments[name]
+	if !exists || value == nil {
+		return 0, nil
+	}
+
+	number, ok := value.(float64)
+	if !ok {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+
+	return number, nil
+}
+
+// optionalInt returns the named integer argument, or zero if it wasn't given
+func optionalInt(request mcp.CallToolRequest, name string) (int, error) {
+	value, exists := request.Params.Arguments[name]
+	if !exists || value == nil {
+		return 0, nil
+	}
+
+	// JSON numbers are decoded as float64
+	number, ok := value.(float64)
+	if !ok || number != float64(int(number)) {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+
+	return int(number), nil
+}
+
 func healthCheckHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ctx, err := agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	// Check if we can get a list of routes as a basic connectivity test
-	_, err := muniClient.GetAllRoutes(ctx)
+	routes, err := muniClient.GetAllRoutes(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("MUNI API health check failed: %v", err)), nil
 	}
 
+	if len(routes) > 0 && routes[0].Stale {
+		return mcp.NewToolResultText("SF MUNI API server is running; serving cached route data while the MUNI API is refreshed"), nil
+	}
+
 	return mcp.NewToolResultText("SF MUNI API server is healthy and running!"), nil
 }
 
 func listAllRoutesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ctx, err := agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	routes, err := muniClient.GetAllRoutes(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch routes: %v", err)), nil
@@ -144,12 +612,26 @@ func getRouteDetailsHandler(ctx context.Context, request mcp.CallToolRequest) (*
 		return mcp.NewToolResultError("route_id must be a string"), nil
 	}
 
+	ctx, err := agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	routeID, err = resolveRouteID(ctx, routeID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
 	details, err := muniClient.GetRouteDetails(ctx, routeID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch route details: %v", err)), nil
 	}
 
-	return newJSONToolResult(details)
+	// Attach the alerts to a copy, leaving the client's details untouched
+	response := *details
+	response.Alerts = activeAlerts(ctx, muni.AlertFilter{RouteID: routeID})
+
+	return newJSONToolResult(response)
 }
 
 func getPredictionsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -158,22 +640,419 @@ func getPredictionsHandler(ctx context.Context, request mcp.CallToolRequest) (*m
 		return mcp.NewToolResultError("route_id must be a string"), nil
 	}
 
-	stopID, ok := request.Params.Arguments["stop_id"].(string)
-	if !ok {
-		return mcp.NewToolResultError("stop_id must be a string"), nil
+	ctx, err := agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	predictions, err := muniClient.GetPredictions(ctx, routeID, stopID)
+	routeID, err = resolveRouteID(ctx, routeID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	stopID, err := requiredStopID(ctx, request.Params.Arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var filter muni.PredictionFilter
+	if filter.Direction, err = optionalString(request, "direction"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if filter.MaxMinutes, err = optionalInt(request, "max_minutes"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if filter.Limit, err = optionalInt(request, "limit"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	predictions, err := muniClient.GetFilteredPredictions(ctx, routeID, stopID, filter)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch predictions: %v", err)), nil
 	}
 
+	result, err := newJSONToolResult(predictions)
+	if err != nil || result.IsError {
+		return result, err
+	}
+
+	return withAlerts(result, activeAlerts(ctx, muni.AlertFilter{RouteID: routeID, StopID: stopID}))
+}
+
+func getStopPredictionsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ctx, err := agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	stopID, err := requiredStopID(ctx, request.Params.Arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	predictions, err := muniClient.GetStopPredictions(ctx, stopID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch stop predictions: %v", err)), nil
+	}
+
 	return newJSONToolResult(predictions)
 }
 
+func getPredictionsBatchHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	items, ok := request.Params.Arguments["requests"].([]interface{})
+	if !ok {
+		return mcp.NewToolResultError("requests must be an array of {route_id, stop_id} objects"), nil
+	}
+
+	ctx, err := agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Pairs whose route or stop can't be resolved get an error on their own
+	// result, like pairs whose predictions can't be fetched
+	results := make([]muni.PredictionResult, len(items))
+	var requests []muni.PredictionRequest
+	var indexes []int
+	for i, item := range items {
+		pair, ok := item.(map[string]interface{})
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("requests[%d] must be an object", i)), nil
+		}
+
+		routeID, ok := pair["route_id"].(string)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("requests[%d].route_id must be a string", i)), nil
+		}
+
+		_, hasID := pair["stop_id"]
+		_, hasCode := pair["stop_code"]
+		if !hasID && !hasCode {
+			return mcp.NewToolResultError(fmt.Sprintf("requests[%d]: stop_id or stop_code is required", i)), nil
+		}
+
+		results[i] = muni.PredictionResult{RouteID: routeID}
+
+		routeID, err = resolveRouteID(ctx, routeID)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].RouteID = routeID
+
+		stopID, err := requiredStopID(ctx, pair)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].StopID = stopID
+
+		requests = append(requests, muni.PredictionRequest{RouteID: routeID, StopID: stopID})
+		indexes = append(indexes, i)
+	}
+
+	if len(items) > 0 && len(requests) == 0 {
+		return newJSONToolResult(results)
+	}
+
+	fetched, err := muniClient.GetPredictionsBatch(ctx, requests)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch predictions: %v", err)), nil
+	}
+
+	for i, result := range fetched {
+		results[indexes[i]] = result
+	}
+
+	return newJSONToolResult(results)
+}
+
+func getVehicleLocationsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	routeID, ok := request.Params.Arguments["route_id"].(string)
+	if !ok {
+		return mcp.NewToolResultError("route_id must be a string"), nil
+	}
+
+	ctx, err := agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	routeID, err = resolveRouteID(ctx, routeID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	vehicles, err := muniClient.GetVehicleLocations(ctx, routeID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch vehicle locations: %v", err)), nil
+	}
+
+	return newJSONToolResult(vehicles)
+}
+
+func getServiceAlertsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var filter muni.AlertFilter
+	var err error
+
+	if filter.RouteID, err = optionalString(request, "route_id"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	ctx, err = agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if filter.RouteID != "" {
+		if filter.RouteID, err = resolveRouteID(ctx, filter.RouteID); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	if filter.StopID, err = stopIDArgument(ctx, request.Params.Arguments); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	alerts, err := muniClient.GetAlerts(ctx, filter)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch service alerts: %v", err)), nil
+	}
+
+	return newJSONToolResult(alerts)
+}
+
+func searchStopsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, ok := request.Params.Arguments["query"].(string)
+	if !ok {
+		return mcp.NewToolResultError("query must be a string"), nil
+	}
+
+	limit, err := optionalInt(request, "limit")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	ctx, err = agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	matches, err := muniClient.SearchStops(ctx, query, limit)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to search stops: %v", err)), nil
+	}
+
+	return newJSONToolResult(matches)
+}
+
+func findNearbyStopsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	lat, ok := request.Params.Arguments["lat"].(float64)
+	if !ok {
+		return mcp.NewToolResultError("lat must be a number"), nil
+	}
+
+	lon, ok := request.Params.Arguments["lon"].(float64)
+	if !ok {
+		return mcp.NewToolResultError("lon must be a number"), nil
+	}
+
+	radius, err := optionalFloat(request, "radius_meters")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	limit, err := optionalInt(request, "limit")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	ctx, err = agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	stops, err := muniClient.FindNearbyStops(ctx, lat, lon, radius, limit)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to find nearby stops: %v", err)), nil
+	}
+
+	return newJSONToolResult(stops)
+}
+
+func getStopDetailsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ctx, err := agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	stopID, err := requiredStopID(ctx, request.Params.Arguments)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	stop, err := muniClient.GetStopDetails(ctx, stopID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch stop details: %v", err)), nil
+	}
+
+	return newJSONToolResult(stop)
+}
+
+func getRouteStopsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	routeID, ok := request.Params.Arguments["route_id"].(string)
+	if !ok {
+		return mcp.NewToolResultError("route_id must be a string"), nil
+	}
+
+	direction, err := optionalString(request, "direction")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	ctx, err = agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	routeID, err = resolveRouteID(ctx, routeID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	directionIDs := []string{direction}
+	if direction == "" {
+		details, err := muniClient.GetRouteDetails(ctx, routeID)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch route details: %v", err)), nil
+		}
+		directionIDs = riderDirectionIDs(details.Directions)
+	}
+
+	sequences := make([]*muni.RouteStopSequence, 0, len(directionIDs))
+	for _, directionID := range directionIDs {
+		sequence, err := muniClient.GetRouteStopSequence(ctx, routeID, directionID)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to fetch route stops: %v", err)), nil
+		}
+		sequences = append(sequences, sequence)
+	}
+
+	return newJSONToolResult(sequences)
+}
+
+func getStopsBetweenHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	routeID, ok := request.Params.Arguments["route_id"].(string)
+	if !ok {
+		return mcp.NewToolResultError("route_id must be a string"), nil
+	}
+
+	ctx, err := agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	routeID, err = resolveRouteID(ctx, routeID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	fromStopID, err := requiredNamedStopID(ctx, request.Params.Arguments, "from_stop")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	toStopID, err := requiredNamedStopID(ctx, request.Params.Arguments, "to_stop")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	ride, err := muniClient.GetStopsBetween(ctx, routeID, fromStopID, toStopID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to find stops between: %v", err)), nil
+	}
+
+	return newJSONToolResult(ride)
+}
+
+func findDirectRoutesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ctx, err := agencyContext(ctx, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	fromStopID, err := requiredNamedStopID(ctx, request.Params.Arguments, "from_stop")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	toStopID, err := requiredNamedStopID(ctx, request.Params.Arguments, "to_stop")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	routes, err := muniClient.FindDirectRoutes(ctx, fromStopID, toStopID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to find direct routes: %v", err)), nil
+	}
+
+	return newJSONToolResult(routes)
+}
+
+// riderDirectionIDs returns the IDs of the directions shown to riders, or of
+// every direction if none are
+func riderDirectionIDs(directions []muni.Direction) []string {
+	var ids, all []string
+	for _, direction := range directions {
+		all = append(all, direction.ID)
+		if direction.UseForUI {
+			ids = append(ids, direction.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return all
+	}
+
+	return ids
+}
+
 func clearCacheHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	muniClient.ClearCache()
-	return mcp.NewToolResultText("MUNI API cache has been cleared"), nil
+	var filter muni.CacheFilter
+	var err error
+
+	if filter.RouteID, err = optionalString(request, "route_id"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if filter.Kind, err = optionalString(request, "kind"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if filter.Prefix, err = optionalString(request, "prefix"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if filter.Agency, err = optionalString(request, "agency_id"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if filter.RouteID != "" {
+		filter.RouteID, err = resolveRouteID(muni.ContextWithAgency(ctx, filter.Agency), filter.RouteID)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	if filter == (muni.CacheFilter{}) {
+		muniClient.ClearCache()
+		return mcp.NewToolResultText("MUNI API cache has been cleared"), nil
+	}
+
+	removed := muniClient.InvalidateCache(filter)
+	return mcp.NewToolResultText(fmt.Sprintf("Removed %d cached MUNI API responses", removed)), nil
 }
 
 func toggleCacheHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -190,3 +1069,7 @@ func toggleCacheHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.
 		return mcp.NewToolResultText("MUNI API caching is now disabled"), nil
 	}
 }
+
+func inspectCacheHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return newJSONToolResult(muniClient.CacheStats())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -160,6 +161,63 @@ func TestListAllRoutesHandler(t *testing.T) {
 	}
 }
 
+func TestAgencyIDArgument(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	var gotAgency string
+	mockClient.GetRouteDetailsFunc = func(ctx context.Context, routeID string) (*muni.RouteDetails, error) {
+		gotAgency = muni.AgencyFromContext(ctx)
+		return &muni.RouteDetails{ID: routeID}, nil
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"route_id":  "1",
+		"agency_id": "actransit",
+	}
+
+	result, err := getRouteDetailsHandler(context.Background(), request)
+
+	// Assert
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if result.IsError {
+		t.Errorf("Expected success, got error result: %+v", result.Content)
+	}
+
+	if gotAgency != "actransit" {
+		t.Errorf("Expected agency to be actransit, got %q", gotAgency)
+	}
+
+	// Test invalid agency_id
+	request.Params.Arguments["agency_id"] = true
+
+	result, err = getRouteDetailsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+}
+
 func TestGetRouteDetailsHandler(t *testing.T) {
 	// Setup
 	originalClient := muniClient
@@ -400,7 +458,7 @@ func TestGetPredictionsHandler(t *testing.T) {
 	}
 }
 
-func TestClearCacheHandler(t *testing.T) {
+func TestGetPredictionsHandlerFilters(t *testing.T) {
 	// Setup
 	originalClient := muniClient
 	defer func() { muniClient = originalClient }()
@@ -408,15 +466,23 @@ func TestClearCacheHandler(t *testing.T) {
 	mockClient := muni.NewMockClient()
 	muniClient = mockClient
 
-	clearCalled := false
-	mockClient.ClearCacheFunc = func() {
-		clearCalled = true
+	var gotFilter muni.PredictionFilter
+	mockClient.GetFilteredPredictionsFunc = func(ctx context.Context, routeID, stopID string, filter muni.PredictionFilter) ([]muni.Prediction, error) {
+		gotFilter = filter
+		return []muni.Prediction{}, nil
 	}
 
-	// Test
-	result, err := clearCacheHandler(context.Background(), mcp.CallToolRequest{})
+	// Test filter arguments are passed through
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"route_id":    "J",
+		"stop_id":     "5678",
+		"direction":   "Inbound",
+		"max_minutes": float64(15),
+		"limit":       float64(2),
+	}
 
-	// Assert
+	result, err := getPredictionsHandler(context.Background(), request)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -425,26 +491,53 @@ func TestClearCacheHandler(t *testing.T) {
 		t.Fatal("Expected result, got nil")
 	}
 
-	if !clearCalled {
-		t.Error("Expected ClearCache to be called")
+	if result.IsError {
+		t.Errorf("Expected success, got error result: %v", result.Content)
 	}
 
-	if len(result.Content) != 1 {
-		t.Errorf("Expected 1 content item, got %d", len(result.Content))
+	expected := muni.PredictionFilter{Direction: "Inbound", MaxMinutes: 15, Limit: 2}
+	if gotFilter != expected {
+		t.Errorf("Expected filter %+v, got %+v", expected, gotFilter)
 	}
 
-	textContent, ok := result.Content[0].(mcp.TextContent)
-	if !ok {
-		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	// Test invalid max_minutes
+	request.Params.Arguments["max_minutes"] = "soon"
+
+	result, err = getPredictionsHandler(context.Background(), request)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
 	}
 
-	expectedText := "MUNI API cache has been cleared"
-	if textContent.Text != expectedText {
-		t.Errorf("Expected text to be '%s', got '%s'", expectedText, textContent.Text)
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+
+	// Test unknown direction
+	mockClient.GetFilteredPredictionsFunc = func(ctx context.Context, routeID, stopID string, filter muni.PredictionFilter) ([]muni.Prediction, error) {
+		return nil, muni.ErrDirectionNotFound
+	}
+	request.Params.Arguments["max_minutes"] = float64(15)
+	request.Params.Arguments["direction"] = "northbound"
+
+	result, err = getPredictionsHandler(context.Background(), request)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
 	}
 }
 
-func TestToggleCacheHandler(t *testing.T) {
+func TestGetStopPredictionsHandler(t *testing.T) {
 	// Setup
 	originalClient := muniClient
 	defer func() { muniClient = originalClient }()
@@ -452,26 +545,110 @@ func TestToggleCacheHandler(t *testing.T) {
 	mockClient := muni.NewMockClient()
 	muniClient = mockClient
 
-	enableCalled := false
-	disableCalled := false
+	// Test success case
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"stop_id": "7142",
+	}
 
-	mockClient.EnableCacheFunc = func() {
-		enableCalled = true
+	result, err := getStopPredictionsHandler(context.Background(), request)
+
+	// Assert success case
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
 	}
 
-	mockClient.DisableCacheFunc = func() {
-		disableCalled = true
+	if result == nil {
+		t.Fatal("Expected result, got nil")
 	}
 
-	// Test enable case
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	}
+
+	var predictions []muni.Prediction
+	if err := json.Unmarshal([]byte(textContent.Text), &predictions); err != nil {
+		t.Fatalf("Failed to unmarshal predictions: %v", err)
+	}
+
+	if len(predictions) != 2 {
+		t.Fatalf("Expected 2 predictions, got %d", len(predictions))
+	}
+
+	if predictions[0].RouteID != "J" || predictions[1].RouteID != "N" {
+		t.Errorf("Expected predictions for routes J and N, got %s and %s", predictions[0].RouteID, predictions[1].RouteID)
+	}
+
+	// Test missing stop_id parameter
+	request = mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{}
+
+	result, err = getStopPredictionsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+
+	// Test API error case
+	mockClient.GetStopPredictionsFunc = func(ctx context.Context, stopID string) ([]muni.Prediction, error) {
+		return nil, errors.New("API error")
+	}
+
+	request.Params.Arguments = map[string]interface{}{
+		"stop_id": "7142",
+	}
+
+	result, err = getStopPredictionsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+}
+
+func TestGetPredictionsBatchHandler(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	mockClient.GetPredictionsFunc = func(ctx context.Context, routeID, stopID string) ([]muni.Prediction, error) {
+		if routeID == "X" {
+			return nil, errors.New("unknown route")
+		}
+		return []muni.Prediction{{VehicleID: "51", Minutes: 4}}, nil
+	}
+
+	// Test success case with a partial failure
 	request := mcp.CallToolRequest{}
 	request.Params.Arguments = map[string]interface{}{
-		"enabled": true,
+		"requests": []interface{}{
+			map[string]interface{}{"route_id": "N", "stop_id": "7142"},
+			map[string]interface{}{"route_id": "X", "stop_id": "7142"},
+		},
 	}
 
-	result, err := toggleCacheHandler(context.Background(), request)
+	result, err := getPredictionsBatchHandler(context.Background(), request)
 
-	// Assert enable case
+	// Assert success case
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -480,26 +657,50 @@ func TestToggleCacheHandler(t *testing.T) {
 		t.Fatal("Expected result, got nil")
 	}
 
-	if !enableCalled {
-		t.Error("Expected EnableCache to be called")
+	if result.IsError {
+		t.Fatalf("Expected success, got error result: %+v", result.Content)
 	}
 
-	if disableCalled {
-		t.Error("DisableCache should not have been called")
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
 	}
 
-	// Test disable case
-	enableCalled = false
-	disableCalled = false
+	var results []muni.PredictionResult
+	if err := json.Unmarshal([]byte(textContent.Text), &results); err != nil {
+		t.Fatalf("Failed to unmarshal results: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	if len(results[0].Predictions) != 1 || results[0].Error != "" {
+		t.Errorf("Expected predictions for the first pair, got %+v", results[0])
+	}
+
+	if results[1].Error != "unknown route" {
+		t.Errorf("Expected error for the second pair, got %+v", results[1])
+	}
+
+	// Test pairs whose route or stop can't be resolved fail on their own
+	mockClient.ResolveRouteFunc = func(ctx context.Context, name string) (*muni.RouteInfo, error) {
+		if name == "Geary" {
+			return nil, &muni.RouteNotFoundError{Query: name}
+		}
+		return &muni.RouteInfo{ID: name}, nil
+	}
 
-	request = mcp.CallToolRequest{}
 	request.Params.Arguments = map[string]interface{}{
-		"enabled": false,
+		"requests": []interface{}{
+			map[string]interface{}{"route_id": "Geary", "stop_id": "7142"},
+			map[string]interface{}{"route_id": "N", "stop_code": "99999"},
+			map[string]interface{}{"route_id": "J", "stop_code": "13860"},
+		},
 	}
 
-	result, err = toggleCacheHandler(context.Background(), request)
+	result, err = getPredictionsBatchHandler(context.Background(), request)
 
-	// Assert disable case
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -508,21 +709,62 @@ func TestToggleCacheHandler(t *testing.T) {
 		t.Fatal("Expected result, got nil")
 	}
 
-	if enableCalled {
-		t.Error("EnableCache should not have been called")
+	if result.IsError {
+		t.Fatalf("Expected success, got error result: %+v", result.Content)
 	}
 
-	if !disableCalled {
-		t.Error("Expected DisableCache to be called")
+	textContent, ok = result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
 	}
 
-	// Test missing parameter case
-	request = mcp.CallToolRequest{}
+	results = nil
+	if err := json.Unmarshal([]byte(textContent.Text), &results); err != nil {
+		t.Fatalf("Failed to unmarshal results: %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	if !strings.Contains(results[0].Error, "Geary") || results[0].RouteID != "Geary" {
+		t.Errorf("Expected an unknown route error for the first pair, got %+v", results[0])
+	}
+
+	if results[1].Error == "" || results[1].RouteID != "N" {
+		t.Errorf("Expected an unknown stop code error for the second pair, got %+v", results[1])
+	}
+
+	if len(results[2].Predictions) != 1 || results[2].StopID != "3860" || results[2].Error != "" {
+		t.Errorf("Expected predictions at stop 3860 for the third pair, got %+v", results[2])
+	}
+
+	// Test malformed pair
+	request.Params.Arguments = map[string]interface{}{
+		"requests": []interface{}{
+			map[string]interface{}{"route_id": "N"},
+		},
+	}
+
+	result, err = getPredictionsBatchHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+
+	// Test missing requests parameter
 	request.Params.Arguments = map[string]interface{}{}
 
-	result, err = toggleCacheHandler(context.Background(), request)
+	result, err = getPredictionsBatchHandler(context.Background(), request)
 
-	// Assert missing parameter case
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -535,3 +777,1359 @@ func TestToggleCacheHandler(t *testing.T) {
 		t.Error("Expected IsError to be true")
 	}
 }
+
+func TestGetVehicleLocationsHandler(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	// Test success case
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"route_id": "N",
+	}
+
+	result, err := getVehicleLocationsHandler(context.Background(), request)
+
+	// Assert success case
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	}
+
+	var vehicles []muni.VehicleLocation
+	if err := json.Unmarshal([]byte(textContent.Text), &vehicles); err != nil {
+		t.Fatalf("Failed to unmarshal vehicles: %v", err)
+	}
+
+	if len(vehicles) != 1 || vehicles[0].RouteID != "N" {
+		t.Errorf("Expected 1 vehicle on route N, got %+v", vehicles)
+	}
+
+	// Test missing route_id parameter
+	request.Params.Arguments = map[string]interface{}{}
+
+	result, err = getVehicleLocationsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+
+	// Test API error case
+	mockClient.GetVehicleLocationsFunc = func(ctx context.Context, routeID string) ([]muni.VehicleLocation, error) {
+		return nil, errors.New("API error")
+	}
+
+	request.Params.Arguments = map[string]interface{}{
+		"route_id": "N",
+	}
+
+	result, err = getVehicleLocationsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+}
+
+func TestGetServiceAlertsHandler(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	var gotFilter muni.AlertFilter
+	mockClient.GetAlertsFunc = func(ctx context.Context, filter muni.AlertFilter) ([]muni.Alert, error) {
+		gotFilter = filter
+		return []muni.Alert{{ID: "alert-1", Severity: muni.AlertSeveritySevere, Header: "N Judah replaced by buses"}}, nil
+	}
+
+	// Test success case
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"route_id": "N",
+		"stop_id":  "7142",
+	}
+
+	result, err := getServiceAlertsHandler(context.Background(), request)
+
+	// Assert success case
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if gotFilter.RouteID != "N" || gotFilter.StopID != "7142" {
+		t.Errorf("Expected filter for route N at stop 7142, got %+v", gotFilter)
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	}
+
+	var alerts []muni.Alert
+	if err := json.Unmarshal([]byte(textContent.Text), &alerts); err != nil {
+		t.Fatalf("Failed to unmarshal alerts: %v", err)
+	}
+
+	if len(alerts) != 1 || alerts[0].ID != "alert-1" {
+		t.Errorf("Expected alert-1, got %+v", alerts)
+	}
+
+	// Test invalid route_id parameter
+	request.Params.Arguments = map[string]interface{}{
+		"route_id": 42,
+	}
+
+	result, err = getServiceAlertsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+
+	// Test API error case
+	mockClient.GetAlertsFunc = func(ctx context.Context, filter muni.AlertFilter) ([]muni.Alert, error) {
+		return nil, errors.New("API error")
+	}
+
+	request.Params.Arguments = map[string]interface{}{}
+
+	result, err = getServiceAlertsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+}
+
+func TestAlertsAttachedToResults(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	mockClient.GetAlertsFunc = func(ctx context.Context, filter muni.AlertFilter) ([]muni.Alert, error) {
+		return []muni.Alert{{ID: "alert-1", Header: "N Judah replaced by buses"}}, nil
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"route_id": "N",
+		"stop_id":  "7142",
+	}
+
+	// Route details carry their alerts
+	result, err := getRouteDetailsHandler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var details muni.RouteDetails
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &details); err != nil {
+		t.Fatalf("Failed to unmarshal route details: %v", err)
+	}
+
+	if len(details.Alerts) != 1 || details.Alerts[0].ID != "alert-1" {
+		t.Errorf("Expected route details to include alert-1, got %+v", details.Alerts)
+	}
+
+	// The details returned by the client aren't modified
+	shared := &muni.RouteDetails{ID: "N", Title: "N-Judah"}
+	mockClient.GetRouteDetailsFunc = func(ctx context.Context, routeID string) (*muni.RouteDetails, error) {
+		return shared, nil
+	}
+
+	if _, err := getRouteDetailsHandler(context.Background(), request); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if shared.Alerts != nil {
+		t.Errorf("Expected the client's route details to be left without alerts, got %+v", shared.Alerts)
+	}
+
+	// Predictions keep their shape, with alerts in a second content item
+	result, err = getPredictionsHandler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(result.Content) != 2 {
+		t.Fatalf("Expected 2 content items, got %d", len(result.Content))
+	}
+
+	var predictions []muni.Prediction
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &predictions); err != nil {
+		t.Fatalf("Failed to unmarshal predictions: %v", err)
+	}
+
+	var attached struct {
+		Alerts []muni.Alert `json:"alerts"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[1].(mcp.TextContent).Text), &attached); err != nil {
+		t.Fatalf("Failed to unmarshal alerts: %v", err)
+	}
+
+	if len(attached.Alerts) != 1 || attached.Alerts[0].ID != "alert-1" {
+		t.Errorf("Expected predictions to include alert-1, got %+v", attached.Alerts)
+	}
+
+	// Alert failures don't fail the request
+	mockClient.GetAlertsFunc = func(ctx context.Context, filter muni.AlertFilter) ([]muni.Alert, error) {
+		return nil, errors.New("API error")
+	}
+
+	result, err = getPredictionsHandler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.IsError || len(result.Content) != 1 {
+		t.Errorf("Expected predictions without alerts, got %+v", result)
+	}
+}
+
+func TestSearchStopsHandler(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	var gotLimit int
+	defaultSearch := mockClient.SearchStopsFunc
+	mockClient.SearchStopsFunc = func(ctx context.Context, query string, limit int) ([]muni.StopMatch, error) {
+		gotLimit = limit
+		return defaultSearch(ctx, query, limit)
+	}
+
+	// Test success case
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"query": "church and duboce",
+		"limit": float64(5),
+	}
+
+	result, err := searchStopsHandler(context.Background(), request)
+
+	// Assert success case
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if gotLimit != 5 {
+		t.Errorf("Expected limit 5, got %d", gotLimit)
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	}
+
+	var matches []muni.StopMatch
+	if err := json.Unmarshal([]byte(textContent.Text), &matches); err != nil {
+		t.Fatalf("Failed to unmarshal matches: %v", err)
+	}
+
+	if len(matches) != 1 || matches[0].ID != "3860" || matches[0].Routes[0].RouteID != "J" {
+		t.Errorf("Expected stop 3860 served by J, got %+v", matches)
+	}
+
+	// Test invalid limit parameter
+	request.Params.Arguments = map[string]interface{}{
+		"query": "church and duboce",
+		"limit": 2.5,
+	}
+
+	result, err = searchStopsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+
+	// Test missing query parameter
+	request.Params.Arguments = map[string]interface{}{}
+
+	result, err = searchStopsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+}
+
+func TestFindNearbyStopsHandler(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	var gotRadius float64
+	var gotLimit int
+	defaultFind := mockClient.FindNearbyStopsFunc
+	mockClient.FindNearbyStopsFunc = func(ctx context.Context, lat, lon, radiusMeters float64, limit int) ([]muni.NearbyStop, error) {
+		gotRadius, gotLimit = radiusMeters, limit
+		return defaultFind(ctx, lat, lon, radiusMeters, limit)
+	}
+
+	// Test success case
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"lat":           37.7693,
+		"lon":           -122.4290,
+		"radius_meters": float64(300),
+		"limit":         float64(3),
+	}
+
+	result, err := findNearbyStopsHandler(context.Background(), request)
+
+	// Assert success case
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if gotRadius != 300 || gotLimit != 3 {
+		t.Errorf("Expected radius 300 and limit 3, got %v and %d", gotRadius, gotLimit)
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	}
+
+	var stops []muni.NearbyStop
+	if err := json.Unmarshal([]byte(textContent.Text), &stops); err != nil {
+		t.Fatalf("Failed to unmarshal stops: %v", err)
+	}
+
+	if len(stops) != 1 || stops[0].ID != "3860" || stops[0].WalkingMinutes != 2 {
+		t.Errorf("Expected stop 3860 two minutes away, got %+v", stops)
+	}
+
+	// Test missing lon parameter
+	request.Params.Arguments = map[string]interface{}{
+		"lat": 37.7693,
+	}
+
+	result, err = findNearbyStopsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+
+	// Test invalid coordinates
+	request.Params.Arguments = map[string]interface{}{
+		"lat": -122.4290,
+		"lon": 37.7693,
+	}
+
+	result, err = findNearbyStopsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+}
+
+func TestStopCodeArgument(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	var gotStopID string
+	mockClient.GetPredictionsFunc = func(ctx context.Context, routeID, stopID string) ([]muni.Prediction, error) {
+		gotStopID = stopID
+		return []muni.Prediction{}, nil
+	}
+
+	// Stop codes are resolved to stop IDs
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"route_id":  "J",
+		"stop_code": "13860",
+	}
+
+	result, err := getPredictionsHandler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.IsError {
+		t.Fatalf("Expected success, got error result: %+v", result.Content)
+	}
+
+	if gotStopID != "3860" {
+		t.Errorf("Expected stop code 13860 to resolve to stop 3860, got %q", gotStopID)
+	}
+
+	// Batch pairs accept stop codes too
+	gotStopID = ""
+	request.Params.Arguments = map[string]interface{}{
+		"requests": []interface{}{
+			map[string]interface{}{"route_id": "J", "stop_code": "13860"},
+		},
+	}
+
+	result, err = getPredictionsBatchHandler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.IsError || gotStopID != "3860" {
+		t.Errorf("Expected batch stop code to resolve to stop 3860, got %q", gotStopID)
+	}
+
+	// Unknown stop codes, and both a stop ID and a stop code, are errors
+	invalid := []map[string]interface{}{
+		{"route_id": "J", "stop_code": "99999"},
+		{"route_id": "J", "stop_id": "3860", "stop_code": "13860"},
+		{"route_id": "J"},
+	}
+
+	for _, args := range invalid {
+		request.Params.Arguments = args
+
+		result, err = getPredictionsHandler(context.Background(), request)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Errorf("Expected error result for %v", args)
+		}
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok || !strings.Contains(textContent.Text, "stop_id or stop_code is required") {
+		t.Errorf("Expected missing stop error, got %+v", result.Content)
+	}
+}
+
+func TestRouteNameArgument(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	mockClient.ResolveRouteFunc = func(ctx context.Context, name string) (*muni.RouteInfo, error) {
+		if name == "N-Judah" {
+			return &muni.RouteInfo{ID: "N", Title: "N Judah"}, nil
+		}
+		return nil, &muni.RouteNotFoundError{
+			Query:       name,
+			Suggestions: []muni.RouteSuggestion{{ID: "38R", Title: "38R Geary Rapid"}},
+		}
+	}
+
+	var gotRouteID string
+	mockClient.GetRouteDetailsFunc = func(ctx context.Context, routeID string) (*muni.RouteDetails, error) {
+		gotRouteID = routeID
+		return &muni.RouteDetails{ID: routeID}, nil
+	}
+
+	// Route names are resolved to route IDs
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"route_id": "N-Judah",
+	}
+
+	result, err := getRouteDetailsHandler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.IsError || gotRouteID != "N" {
+		t.Errorf("Expected N-Judah to resolve to N, got %q", gotRouteID)
+	}
+
+	// Unknown routes come back with suggestions
+	request.Params.Arguments = map[string]interface{}{
+		"route_id": "39R",
+	}
+
+	result, err = getRouteDetailsHandler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !result.IsError {
+		t.Fatal("Expected IsError to be true")
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok || !strings.Contains(textContent.Text, "did you mean 38R") {
+		t.Errorf("Expected a did you mean suggestion, got %+v", result.Content)
+	}
+
+	// Names are used as given when the route list is unavailable
+	mockClient.ResolveRouteFunc = func(ctx context.Context, name string) (*muni.RouteInfo, error) {
+		return nil, errors.New("API error")
+	}
+
+	result, err = getRouteDetailsHandler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if result.IsError || gotRouteID != "39R" {
+		t.Errorf("Expected route 39R to be used as given, got %q", gotRouteID)
+	}
+}
+
+func TestGetStopDetailsHandler(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	// Test success case
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"stop_id": "3860",
+	}
+
+	result, err := getStopDetailsHandler(context.Background(), request)
+
+	// Assert success case
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	}
+
+	var stop muni.StopInfo
+	if err := json.Unmarshal([]byte(textContent.Text), &stop); err != nil {
+		t.Fatalf("Failed to unmarshal stop: %v", err)
+	}
+
+	if stop.ID != "3860" || len(stop.Routes) != 2 {
+		t.Errorf("Expected stop 3860 served by 2 routes, got %+v", stop)
+	}
+
+	if stop.Routes[0].RouteColor != "a96614" {
+		t.Errorf("Expected route color a96614, got %s", stop.Routes[0].RouteColor)
+	}
+
+	// Test missing stop parameters
+	request.Params.Arguments = map[string]interface{}{}
+
+	result, err = getStopDetailsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+
+	// Test API error case
+	mockClient.GetStopDetailsFunc = func(ctx context.Context, stopID string) (*muni.StopInfo, error) {
+		return nil, muni.ErrStopNotFound
+	}
+
+	request.Params.Arguments = map[string]interface{}{
+		"stop_id": "9999",
+	}
+
+	result, err = getStopDetailsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+}
+
+func TestGetRouteStopsHandler(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	// Test every direction by default
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"route_id": "J",
+	}
+
+	result, err := getRouteStopsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	}
+
+	var sequences []muni.RouteStopSequence
+	if err := json.Unmarshal([]byte(textContent.Text), &sequences); err != nil {
+		t.Fatalf("Failed to unmarshal route stops: %v", err)
+	}
+
+	if len(sequences) != 1 || sequences[0].DirectionID != "DIR_1" {
+		t.Fatalf("Expected one sequence for DIR_1, got %+v", sequences)
+	}
+
+	if len(sequences[0].Stops) != 1 || !sequences[0].Stops[0].IsFirst || !sequences[0].Stops[0].IsLast {
+		t.Errorf("Expected a single terminal stop, got %+v", sequences[0].Stops)
+	}
+
+	// Test a single direction by name
+	var gotDirection string
+	mockClient.GetRouteStopSequenceFunc = func(ctx context.Context, routeID, directionID string) (*muni.RouteStopSequence, error) {
+		gotDirection = directionID
+		return &muni.RouteStopSequence{RouteID: routeID, DirectionID: "DIR_1", Stops: []muni.RouteStop{}}, nil
+	}
+	request.Params.Arguments["direction"] = "Inbound"
+
+	result, err = getRouteStopsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil || result.IsError {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+
+	if gotDirection != "Inbound" {
+		t.Errorf("Expected direction Inbound to be passed through, got %s", gotDirection)
+	}
+
+	// Test missing route_id
+	request.Params.Arguments = map[string]interface{}{}
+
+	result, err = getRouteStopsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+
+	// Test API error case
+	mockClient.GetRouteStopSequenceFunc = func(ctx context.Context, routeID, directionID string) (*muni.RouteStopSequence, error) {
+		return nil, muni.ErrDirectionNotFound
+	}
+	request.Params.Arguments = map[string]interface{}{
+		"route_id":  "J",
+		"direction": "northbound",
+	}
+
+	result, err = getRouteStopsHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+}
+
+func TestGetStopsBetweenHandler(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	// Test success case, with the destination given by stop code
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"route_id":     "K",
+		"from_stop_id": "5728",
+		"to_stop_code": "13860",
+	}
+
+	var gotFrom, gotTo string
+	mockClient.GetStopsBetweenFunc = func(ctx context.Context, routeID, fromStopID, toStopID string) (*muni.StopsBetween, error) {
+		gotFrom, gotTo = fromStopID, toStopID
+		return &muni.StopsBetween{RouteID: routeID, StopCount: 4, Stops: []muni.RouteStop{}}, nil
+	}
+
+	result, err := getStopsBetweenHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	}
+
+	var ride muni.StopsBetween
+	if err := json.Unmarshal([]byte(textContent.Text), &ride); err != nil {
+		t.Fatalf("Failed to unmarshal stops between: %v", err)
+	}
+
+	if ride.StopCount != 4 {
+		t.Errorf("Expected stop count 4, got %d", ride.StopCount)
+	}
+
+	if gotFrom != "5728" || gotTo != "3860" {
+		t.Errorf("Expected stops 5728 and 3860, got %s and %s", gotFrom, gotTo)
+	}
+
+	// Test missing destination
+	request.Params.Arguments = map[string]interface{}{
+		"route_id":     "K",
+		"from_stop_id": "5728",
+	}
+
+	result, err = getStopsBetweenHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+
+	textContent, ok = result.Content[0].(mcp.TextContent)
+	if !ok || textContent.Text != "to_stop_id or to_stop_code is required" {
+		t.Errorf("Expected missing to_stop error, got %+v", result.Content[0])
+	}
+
+	// Test API error case
+	mockClient.GetStopsBetweenFunc = func(ctx context.Context, routeID, fromStopID, toStopID string) (*muni.StopsBetween, error) {
+		return nil, muni.ErrStopsNotInSameDirection
+	}
+	request.Params.Arguments["to_stop_id"] = "7142"
+
+	result, err = getStopsBetweenHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+}
+
+func TestFindDirectRoutesHandler(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	// Test success case, with the origin given by stop code
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"from_stop_code": "13860",
+		"to_stop_id":     "7142",
+	}
+
+	result, err := findDirectRoutesHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	}
+
+	var routes []muni.DirectRoute
+	if err := json.Unmarshal([]byte(textContent.Text), &routes); err != nil {
+		t.Fatalf("Failed to unmarshal direct routes: %v", err)
+	}
+
+	if len(routes) != 1 {
+		t.Fatalf("Expected 1 direct route, got %d", len(routes))
+	}
+
+	if routes[0].RouteID != "J" || routes[0].From.ID != "3860" || routes[0].To.ID != "7142" {
+		t.Errorf("Expected J from 3860 to 7142, got %s from %s to %s", routes[0].RouteID, routes[0].From.ID, routes[0].To.ID)
+	}
+
+	if len(routes[0].Predictions) != 1 {
+		t.Errorf("Expected 1 prediction, got %d", len(routes[0].Predictions))
+	}
+
+	// Test missing origin
+	request.Params.Arguments = map[string]interface{}{
+		"to_stop_id": "7142",
+	}
+
+	result, err = findDirectRoutesHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+
+	// Test API error case
+	mockClient.FindDirectRoutesFunc = func(ctx context.Context, fromStopID, toStopID string) ([]muni.DirectRoute, error) {
+		return nil, muni.ErrStopNotFound
+	}
+	request.Params.Arguments["from_stop_id"] = "9999"
+
+	result, err = findDirectRoutesHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+}
+
+func TestClearCacheHandler(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	clearCalled := false
+	mockClient.ClearCacheFunc = func() {
+		clearCalled = true
+	}
+
+	// Test
+	result, err := clearCacheHandler(context.Background(), mcp.CallToolRequest{})
+
+	// Assert
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !clearCalled {
+		t.Error("Expected ClearCache to be called")
+	}
+
+	if len(result.Content) != 1 {
+		t.Errorf("Expected 1 content item, got %d", len(result.Content))
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	}
+
+	expectedText := "MUNI API cache has been cleared"
+	if textContent.Text != expectedText {
+		t.Errorf("Expected text to be '%s', got '%s'", expectedText, textContent.Text)
+	}
+}
+
+func TestClearCacheHandlerWithFilter(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	clearCalled := false
+	mockClient.ClearCacheFunc = func() {
+		clearCalled = true
+	}
+
+	var gotFilter muni.CacheFilter
+	mockClient.InvalidateCacheFunc = func(filter muni.CacheFilter) int {
+		gotFilter = filter
+		return 2
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"route_id": "N",
+		"kind":     "predictions",
+	}
+
+	result, err := clearCacheHandler(context.Background(), request)
+
+	// Assert
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if clearCalled {
+		t.Error("ClearCache should not have been called")
+	}
+
+	expectedFilter := muni.CacheFilter{RouteID: "N", Kind: "predictions"}
+	if gotFilter != expectedFilter {
+		t.Errorf("Expected filter %+v, got %+v", expectedFilter, gotFilter)
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	}
+
+	expectedText := "Removed 2 cached MUNI API responses"
+	if textContent.Text != expectedText {
+		t.Errorf("Expected text to be '%s', got '%s'", expectedText, textContent.Text)
+	}
+
+	// Test route names are resolved, and unknown routes rejected
+	mockClient.ResolveRouteFunc = func(ctx context.Context, name string) (*muni.RouteInfo, error) {
+		if name == "N-Judah" {
+			return &muni.RouteInfo{ID: "N", Title: "N Judah"}, nil
+		}
+		return nil, &muni.RouteNotFoundError{Query: name}
+	}
+
+	request.Params.Arguments = map[string]interface{}{
+		"route_id": "N-Judah",
+	}
+
+	result, err = clearCacheHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil || result.IsError {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+
+	if gotFilter.RouteID != "N" {
+		t.Errorf("Expected N-Judah to resolve to N, got %q", gotFilter.RouteID)
+	}
+
+	request.Params.Arguments = map[string]interface{}{
+		"route_id": "Geary",
+	}
+
+	result, err = clearCacheHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true for an unknown route")
+	}
+
+	// Test invalid argument type
+	request = mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"route_id": 38,
+	}
+
+	result, err = clearCacheHandler(context.Background(), request)
+
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+}
+
+func TestToggleCacheHandler(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	enableCalled := false
+	disableCalled := false
+
+	mockClient.EnableCacheFunc = func() {
+		enableCalled = true
+	}
+
+	mockClient.DisableCacheFunc = func() {
+		disableCalled = true
+	}
+
+	// Test enable case
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"enabled": true,
+	}
+
+	result, err := toggleCacheHandler(context.Background(), request)
+
+	// Assert enable case
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !enableCalled {
+		t.Error("Expected EnableCache to be called")
+	}
+
+	if disableCalled {
+		t.Error("DisableCache should not have been called")
+	}
+
+	// Test disable case
+	enableCalled = false
+	disableCalled = false
+
+	request = mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{
+		"enabled": false,
+	}
+
+	result, err = toggleCacheHandler(context.Background(), request)
+
+	// Assert disable case
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if enableCalled {
+		t.Error("EnableCache should not have been called")
+	}
+
+	if !disableCalled {
+		t.Error("Expected DisableCache to be called")
+	}
+
+	// Test missing parameter case
+	request = mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]interface{}{}
+
+	result, err = toggleCacheHandler(context.Background(), request)
+
+	// Assert missing parameter case
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	if !result.IsError {
+		t.Error("Expected IsError to be true")
+	}
+}
+
+func TestInspectCacheHandler(t *testing.T) {
+	// Setup
+	originalClient := muniClient
+	defer func() { muniClient = originalClient }()
+
+	mockClient := muni.NewMockClient()
+	muniClient = mockClient
+
+	mockClient.CacheStatsFunc = func() muni.CacheStats {
+		return muni.CacheStats{
+			Enabled:  true,
+			Entries:  1,
+			Hits:     3,
+			Misses:   1,
+			HitRatio: 0.75,
+			Keys: []muni.CacheKeyStats{
+				{Key: "route_details:N", Kind: "route_details", Bytes: 1024, AgeSeconds: 30},
+			},
+		}
+	}
+
+	result, err := inspectCacheHandler(context.Background(), mcp.CallToolRequest{})
+
+	// Assert
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	textContent, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("Expected TextContent, got %T", result.Content[0])
+	}
+
+	var stats muni.CacheStats
+	if err := json.Unmarshal([]byte(textContent.Text), &stats); err != nil {
+		t.Fatalf("Failed to unmarshal cache stats: %v", err)
+	}
+
+	if stats.HitRatio != 0.75 {
+		t.Errorf("Expected hit ratio 0.75, got %v", stats.HitRatio)
+	}
+
+	if len(stats.Keys) != 1 || stats.Keys[0].Key != "route_details:N" {
+		t.Errorf("Expected route_details:N key, got %+v", stats.Keys)
+	}
+}
+
+func TestClientOptionsFromEnv(t *testing.T) {
+	// Default configuration
+	opts, err := clientOptionsFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(opts) != 1 {
+		t.Errorf("Expected 1 default option, got %d", len(opts))
+	}
+
+	// Rate limit configuration
+	t.Setenv("MUNI_RATE_LIMIT", "5")
+	t.Setenv("MUNI_RATE_LIMIT_BURST", "10")
+	t.Setenv("MUNI_RATE_LIMIT_REJECT", "true")
+
+	opts, err = clientOptionsFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(opts) != 3 {
+		t.Errorf("Expected 3 options with rate limiting, got %d", len(opts))
+	}
+
+	// Prediction caching
+	t.Setenv("MUNI_PREDICTION_TTL", "15s")
+
+	opts, err = clientOptionsFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(opts) != 4 {
+		t.Errorf("Expected 4 options with prediction caching, got %d", len(opts))
+	}
+
+	t.Setenv("MUNI_PREDICTION_TTL", "soon")
+
+	if _, err := clientOptionsFromEnv(); err == nil {
+		t.Error("Expected error for invalid MUNI_PREDICTION_TTL")
+	}
+
+	t.Setenv("MUNI_PREDICTION_TTL", "")
+
+	// Persistent cache
+	t.Setenv("MUNI_CACHE_DIR", t.TempDir())
+
+	opts, err = clientOptionsFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(opts) != 4 {
+		t.Errorf("Expected 4 options with a cache directory, got %d", len(opts))
+	}
+
+	// Agency
+	t.Setenv("MUNI_AGENCY", "actransit")
+
+	opts, err = clientOptionsFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(opts) != 5 {
+		t.Errorf("Expected 5 options with an agency, got %d", len(opts))
+	}
+
+	// Invalid rate limit
+	t.Setenv("MUNI_RATE_LIMIT", "fast")
+
+	if _, err := clientOptionsFromEnv(); err == nil {
+		t.Error("Expected error for invalid MUNI_RATE_LIMIT")
+	}
+
+	// Invalid burst
+	t.Setenv("MUNI_RATE_LIMIT", "5")
+	t.Setenv("MUNI_RATE_LIMIT_BURST", "0")
+
+	if _, err := clientOptionsFromEnv(); err == nil {
+		t.Error("Expected error for invalid MUNI_RATE_LIMIT_BURST")
+	}
+}
@@ -0,0 +1,30 @@
+package muni
+
+import "context"
+
+// DefaultAgency is the umoiq agency ID for SF MUNI
+const DefaultAgency = "sfmta-cis"
+
+// agencyContextKey is the context key for per-request agency overrides
+type agencyContextKey struct{}
+
+// ContextWithAgency returns a context that makes client requests made with it
+// use the given agency instead of the client's default. An empty agency ID
+// leaves the client's default in place.
+func ContextWithAgency(ctx context.Context, agencyID string) context.Context {
+	return context.WithValue(ctx, agencyContextKey{}, agencyID)
+}
+
+// AgencyFromContext returns the agency set with ContextWithAgency, or an empty string
+func AgencyFromContext(ctx context.Context) string {
+	agencyID, _ := ctx.Value(agencyContextKey{}).(string)
+	return agencyID
+}
+
+// agencyFor returns the agency to use for a request made with ctx
+func (c *Client) agencyFor(ctx context.Context) string {
+	if agencyID := AgencyFromContext(ctx); agencyID != "" {
+		return agencyID
+	}
+	return c.agency
+}
@@ -0,0 +1,79 @@
+package muni
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// pathRecordingServer serves the given response and records the requested paths
+func pathRecordingServer(response string, paths *[]string, mutex *sync.Mutex) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		*paths = append(*paths, r.URL.Path)
+		mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+}
+
+func TestAgencySelection(t *testing.T) {
+	var paths []string
+	var mutex sync.Mutex
+	server := pathRecordingServer(mockRoutesResponse, &paths, &mutex)
+	defer server.Close()
+
+	// Default agency
+	client := NewClient(server.URL)
+	defer client.Close()
+
+	if _, err := client.GetAllRoutes(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Client-wide agency
+	otherClient := NewClient(server.URL, WithAgency("actransit"))
+	defer otherClient.Close()
+
+	if _, err := otherClient.GetAllRoutes(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Per-request agency, cached separately from the default agency
+	ctx := ContextWithAgency(context.Background(), "sf-muni-alt")
+	if _, err := client.GetAllRoutes(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := client.GetAllRoutes(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"/v2.0/riders/agencies/sfmta-cis/routes",
+		"/v2.0/riders/agencies/actransit/routes",
+		"/v2.0/riders/agencies/sf-muni-alt/routes",
+	}
+
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected %d requests, got %v", len(expected), paths)
+	}
+
+	for i, path := range expected {
+		if paths[i] != path {
+			t.Errorf("Expected request %d to be %s, got %s", i, path, paths[i])
+		}
+	}
+
+	stats := client.CacheStats()
+	if len(stats.Keys) != 2 {
+		t.Fatalf("Expected 2 cached entries, got %+v", stats.Keys)
+	}
+
+	if stats.Keys[0].Key != "all_routes:sf-muni-alt" || stats.Keys[1].Key != "all_routes:sfmta-cis" {
+		t.Errorf("Expected cache keys namespaced by agency, got %s and %s", stats.Keys[0].Key, stats.Keys[1].Key)
+	}
+}
@@ -0,0 +1,218 @@
+package muni
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultAlertTTL is how long service alerts are cached by default
+const defaultAlertTTL = time.Minute
+
+// AlertSeverity describes how disruptive a service alert is
+type AlertSeverity string
+
+// Severity levels reported for service alerts
+const (
+	AlertSeverityUnknown AlertSeverity = "unknown"
+	AlertSeverityInfo    AlertSeverity = "info"
+	AlertSeverityWarning AlertSeverity = "warning"
+	AlertSeveritySevere  AlertSeverity = "severe"
+)
+
+// parseAlertSeverity converts the API's severity level to an AlertSeverity
+func parseAlertSeverity(severity string) AlertSeverity {
+	switch strings.ToUpper(severity) {
+	case "INFO":
+		return AlertSeverityInfo
+	case "WARNING":
+		return AlertSeverityWarning
+	case "SEVERE":
+		return AlertSeveritySevere
+	default:
+		return AlertSeverityUnknown
+	}
+}
+
+// AlertResponse represents a rider alert as returned by the alerts API.
+// Times are in milliseconds since the epoch.
+type AlertResponse struct {
+	ID            string                `json:"id"`
+	Severity      string                `json:"severity"`
+	Cause         string                `json:"cause"`
+	Effect        string                `json:"effect"`
+	Header        string                `json:"header"`
+	Description   string                `json:"description"`
+	URL           string                `json:"url"`
+	ActivePeriods []AlertPeriodResponse `json:"activePeriods"`
+	Affected      []AlertEntity         `json:"affected"`
+}
+
+// AlertPeriodResponse represents when an alert is in effect. An end of zero
+// means the alert is in effect until further notice.
+type AlertPeriodResponse struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// AlertEntity is a route, stop, or stop on a route affected by an alert. An
+// entity with neither a route nor a stop affects the whole agency.
+type AlertEntity struct {
+	RouteID string `json:"routeId,omitempty"`
+	StopID  string `json:"stopId,omitempty"`
+}
+
+// matches reports whether the entity affects the given route and stop.
+// Empty arguments match any route or stop. Route-wide entities only match a
+// stop when the route is given too, since the stop may not be on that route.
+func (e AlertEntity) matches(routeID, stopID string) bool {
+	if routeID != "" && e.RouteID != "" && !strings.EqualFold(e.RouteID, routeID) {
+		return false
+	}
+
+	if stopID != "" && e.StopID != stopID {
+		return e.StopID == "" && (e.RouteID == "" || routeID != "")
+	}
+
+	return true
+}
+
+// AlertPeriod is when an alert is in effect. A nil End means until further notice.
+type AlertPeriod struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// contains reports whether t falls within the period
+func (p AlertPeriod) contains(t time.Time) bool {
+	if t.Before(p.Start) {
+		return false
+	}
+	return p.End == nil || t.Before(*p.End)
+}
+
+// Alert represents a rider alert such as a detour, elevator outage or line shutdown
+type Alert struct {
+	ID            string        `json:"id"`
+	Severity      AlertSeverity `json:"severity"`
+	Cause         string        `json:"cause,omitempty"`
+	Effect        string        `json:"effect,omitempty"`
+	Header        string        `json:"header"`
+	Description   string        `json:"description,omitempty"`
+	URL           string        `json:"url,omitempty"`
+	ActivePeriods []AlertPeriod `json:"active_periods,omitempty"`
+	RouteIDs      []string      `json:"route_ids,omitempty"`
+	StopIDs       []string      `json:"stop_ids,omitempty"`
+	Affected      []AlertEntity `json:"affected,omitempty"`
+}
+
+// IsActive reports whether the alert is in effect at t. Alerts without
+// active periods are always in effect.
+func (a Alert) IsActive(t time.Time) bool {
+	if len(a.ActivePeriods) == 0 {
+		return true
+	}
+
+	for _, period := range a.ActivePeriods {
+		if period.contains(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Affects reports whether the alert applies to the given route and stop.
+// Empty arguments match any route or stop, so Affects("N", "") is true for
+// alerts about the whole N line or any stop on it, and Affects("N", "1234")
+// is true for alerts about the whole N line or stop 1234 on it.
+func (a Alert) Affects(routeID, stopID string) bool {
+	if len(a.Affected) == 0 {
+		return true
+	}
+
+	for _, entity := range a.Affected {
+		if entity.matches(routeID, stopID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AlertFilter selects service alerts. Empty fields match every alert.
+type AlertFilter struct {
+	RouteID string
+	StopID  string
+}
+
+// GetAlerts fetches the service alerts currently in effect, limited to those
+// affecting the filter's route and stop
+func (c *Client) GetAlerts(ctx context.Context, filter AlertFilter) ([]Alert, error) {
+	agency := c.agencyFor(ctx)
+	cacheKey := buildCacheKey(CacheKindAlerts, agency)
+
+	result, err := fetchCached(ctx, c, cacheKey, func(ctx context.Context) ([]AlertResponse, error) {
+		url := fmt.Sprintf("%s/v2.0/riders/agencies/%s/alerts", c.baseURL, agency)
+
+		var alerts []AlertResponse
+		if err := c.fetchJSON(ctx, EndpointAlerts, url, &alerts); err != nil {
+			return nil, err
+		}
+
+		return alerts, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	alerts := []Alert{}
+	for _, response := range result.value {
+		alert := convertAlert(response)
+		if alert.IsActive(now) && alert.Affects(filter.RouteID, filter.StopID) {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	return alerts, nil
+}
+
+// convertAlert converts an alert response to an Alert
+func convertAlert(response AlertResponse) Alert {
+	alert := Alert{
+		ID:          response.ID,
+		Severity:    parseAlertSeverity(response.Severity),
+		Cause:       response.Cause,
+		Effect:      response.Effect,
+		Header:      response.Header,
+		Description: response.Description,
+		URL:         response.URL,
+		Affected:    response.Affected,
+	}
+
+	for _, period := range response.ActivePeriods {
+		converted := AlertPeriod{Start: time.UnixMilli(period.Start)}
+		if period.End > 0 {
+			end := time.UnixMilli(period.End)
+			converted.End = &end
+		}
+		alert.ActivePeriods = append(alert.ActivePeriods, converted)
+	}
+
+	seenRoutes := make(map[string]bool)
+	seenStops := make(map[string]bool)
+	for _, entity := range response.Affected {
+		if entity.RouteID != "" && !seenRoutes[entity.RouteID] {
+			seenRoutes[entity.RouteID] = true
+			alert.RouteIDs = append(alert.RouteIDs, entity.RouteID)
+		}
+		if entity.StopID != "" && !seenStops[entity.StopID] {
+			seenStops[entity.StopID] = true
+			alert.StopIDs = append(alert.StopIDs, entity.StopID)
+		}
+	}
+
+	return alert
+}
@@ -0,0 +1,128 @@
+package muni
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetAlerts(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	// Expired alerts are left out
+	alerts, err := client.GetAlerts(context.Background(), AlertFilter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(alerts) != 2 {
+		t.Fatalf("Expected 2 active alerts, got %d", len(alerts))
+	}
+
+	alert := alerts[0]
+	if alert.ID != "alert-1" || alert.Severity != AlertSeveritySevere {
+		t.Errorf("Expected severe alert-1, got %s %s", alert.ID, alert.Severity)
+	}
+
+	if len(alert.RouteIDs) != 1 || alert.RouteIDs[0] != "N" {
+		t.Errorf("Expected alert-1 to affect route N, got %v", alert.RouteIDs)
+	}
+
+	if len(alert.ActivePeriods) != 1 || alert.ActivePeriods[0].End != nil {
+		t.Errorf("Expected one open-ended active period, got %+v", alert.ActivePeriods)
+	}
+
+	tests := []struct {
+		filter   AlertFilter
+		expected []string
+	}{
+		{AlertFilter{RouteID: "N"}, []string{"alert-1"}},
+		{AlertFilter{RouteID: "j"}, []string{"alert-2"}},
+		{AlertFilter{StopID: "1234"}, []string{"alert-2"}},
+		{AlertFilter{RouteID: "N", StopID: "1234"}, []string{"alert-1"}},
+		{AlertFilter{RouteID: "J", StopID: "1234"}, []string{"alert-2"}},
+		{AlertFilter{RouteID: "J", StopID: "5678"}, []string{}},
+	}
+
+	for _, test := range tests {
+		alerts, err := client.GetAlerts(context.Background(), test.filter)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		ids := []string{}
+		for _, alert := range alerts {
+			ids = append(ids, alert.ID)
+		}
+
+		if len(ids) != len(test.expected) {
+			t.Errorf("Expected alerts %v for %+v, got %v", test.expected, test.filter, ids)
+			continue
+		}
+
+		for i := range ids {
+			if ids[i] != test.expected[i] {
+				t.Errorf("Expected alerts %v for %+v, got %v", test.expected, test.filter, ids)
+				break
+			}
+		}
+	}
+}
+
+func TestExpiredAlertsNotServedStale(t *testing.T) {
+	var failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(mockAlertsResponse))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithAlertTTL(time.Millisecond), WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	if _, err := client.GetAlerts(context.Background(), AlertFilter{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Let the alerts expire and take the upstream down
+	time.Sleep(5 * time.Millisecond)
+	atomic.StoreInt32(&failing, 1)
+
+	if _, err := client.GetAlerts(context.Background(), AlertFilter{}); err == nil {
+		t.Error("Expected an error instead of expired alerts")
+	}
+}
+
+func TestAlertIsActive(t *testing.T) {
+	start := time.Date(2024, 3, 20, 8, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	alert := Alert{ActivePeriods: []AlertPeriod{{Start: start, End: &end}}}
+
+	if alert.IsActive(start.Add(-time.Minute)) {
+		t.Error("Expected alert not to be active before it starts")
+	}
+
+	if !alert.IsActive(start.Add(time.Minute)) {
+		t.Error("Expected alert to be active during its period")
+	}
+
+	if alert.IsActive(end) {
+		t.Error("Expected alert not to be active once it ends")
+	}
+
+	if !(Alert{}).IsActive(start) {
+		t.Error("Expected alert without active periods to always be active")
+	}
+}
@@ -0,0 +1,78 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Batch limits
+const (
+	batchConcurrency       = 4
+	maxPredictionBatchSize = 50
+)
+
+// Batch errors
+var (
+	ErrEmptyBatch    = errors.New("at least one route/stop pair is required")
+	ErrBatchTooLarge = fmt.Errorf("at most %d route/stop pairs are allowed", maxPredictionBatchSize)
+)
+
+// PredictionRequest identifies a route and stop to fetch predictions for
+type PredictionRequest struct {
+	RouteID string `json:"route_id"`
+	StopID  string `json:"stop_id"`
+}
+
+// PredictionResult holds the predictions, or the error, for one request in a batch
+type PredictionResult struct {
+	RouteID     string       `json:"route_id"`
+	StopID      string       `json:"stop_id"`
+	Predictions []Prediction `json:"predictions,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// GetPredictionsBatch fetches predictions for many route/stop pairs with
+// bounded concurrency. Results are returned in request order; a failure for
+// one pair is reported on its result without failing the rest of the batch.
+func (c *Client) GetPredictionsBatch(ctx context.Context, requests []PredictionRequest) ([]PredictionResult, error) {
+	if len(requests) == 0 {
+		return nil, ErrEmptyBatch
+	}
+
+	if len(requests) > maxPredictionBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	results := make([]PredictionResult, len(requests))
+	sem := make(chan struct{}, batchConcurrency)
+
+	var wg sync.WaitGroup
+	for i, request := range requests {
+		results[i] = PredictionResult{RouteID: request.RouteID, StopID: request.StopID}
+
+		wg.Add(1)
+		go func(result *PredictionResult) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				result.Error = ctx.Err().Error()
+				return
+			}
+
+			predictions, err := c.GetPredictions(ctx, result.RouteID, result.StopID)
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+			result.Predictions = predictions
+		}(&results[i])
+	}
+	wg.Wait()
+
+	return results, nil
+}
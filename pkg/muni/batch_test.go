@@ -0,0 +1,56 @@
+package muni
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetPredictionsBatch(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	results, err := client.GetPredictionsBatch(context.Background(), []PredictionRequest{
+		{RouteID: "N", StopID: "1234"},
+		{RouteID: "J", StopID: "5678"},
+		{RouteID: "X", StopID: "1234"},
+		{RouteID: "N", StopID: ""},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+
+	// Results keep the request order
+	if results[0].RouteID != "N" || len(results[0].Predictions) != 1 || results[0].Error != "" {
+		t.Errorf("Expected 1 prediction for N:1234, got %+v", results[0])
+	}
+
+	if results[1].RouteID != "J" || len(results[1].Predictions) != 2 || results[1].Error != "" {
+		t.Errorf("Expected 2 predictions for J:5678, got %+v", results[1])
+	}
+
+	// Failures are reported per pair
+	if results[2].Error == "" {
+		t.Error("Expected an error for unknown route X")
+	}
+
+	if results[3].Error != ErrStopIDRequired.Error() {
+		t.Errorf("Expected %q for missing stop ID, got %q", ErrStopIDRequired.Error(), results[3].Error)
+	}
+
+	// Batch size limits
+	if _, err := client.GetPredictionsBatch(context.Background(), nil); err != ErrEmptyBatch {
+		t.Errorf("Expected ErrEmptyBatch, got %v", err)
+	}
+
+	tooMany := make([]PredictionRequest, maxPredictionBatchSize+1)
+	if _, err := client.GetPredictionsBatch(context.Background(), tooMany); err != ErrBatchTooLarge {
+		t.Errorf("Expected ErrBatchTooLarge, got %v", err)
+	}
+}
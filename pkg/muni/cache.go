@@ -0,0 +1,502 @@
+package muni
+
+import (
+	"container/list"
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache kinds group cache entries that share a TTL. Cache keys have the form
+// kind:agency[:route[:stop]], e.g. "route_details:sfmta-cis:N".
+const (
+	CacheKindAllRoutes    = "all_routes"
+	CacheKindRouteDetails = "route_details"
+	CacheKindPredictions  = "predictions"
+	CacheKindVehicles     = "vehicles"
+	CacheKindAlerts       = "alerts"
+)
+
+// Default cache limits
+const (
+	defaultCacheStaleWindow     = time.Hour
+	defaultCacheMaxEntries      = 1000
+	defaultCacheMaxBytes        = 64 << 20 // 64 MiB
+	defaultCacheJanitorInterval = time.Minute
+)
+
+// cacheState describes the freshness of a cache lookup
+type cacheState int
+
+const (
+	cacheMiss cacheState = iota
+	cacheFresh
+	cacheStale
+)
+
+// cacheEntry represents a cached item with expiration. Expired entries may
+// still be served as stale data until staleUntil.
+type cacheEntry struct {
+	key        string
+	kind       string
+	data       json.RawMessage
+	storedAt   time.Time
+	expiration time.Time
+	staleUntil time.Time
+	hits       int64
+}
+
+// isExpired checks if the cache entry has expired
+func (c *cacheEntry) isExpired() bool {
+	return time.Now().After(c.expiration)
+}
+
+// isDead checks if the cache entry has expired and can no longer be served as stale
+func (c *cacheEntry) isDead() bool {
+	return time.Now().After(c.staleUntil)
+}
+
+// size approximates the memory used by the entry
+func (c *cacheEntry) size() int {
+	return len(c.key) + len(c.data)
+}
+
+// buildCacheKey builds the cache key for data of the given kind and agency
+func buildCacheKey(kind, agency string, parts ...string) string {
+	return strings.Join(append([]string{kind, agency}, parts...), ":")
+}
+
+// cacheKind returns the kind of a cache key
+func cacheKind(key string) string {
+	kind, _, _ := strings.Cut(key, ":")
+	return kind
+}
+
+// cacheKeyAgency returns the agency a cache key belongs to
+func cacheKeyAgency(key string) string {
+	parts := strings.Split(key, ":")
+	if len(parts) > 1 {
+		return parts[1]
+	}
+	return ""
+}
+
+// cacheKeyRouteID returns the route a cache key belongs to, if any
+func cacheKeyRouteID(key string) string {
+	parts := strings.Split(key, ":")
+	switch parts[0] {
+	case CacheKindRouteDetails, CacheKindPredictions, CacheKindVehicles:
+		if len(parts) > 2 {
+			return parts[2]
+		}
+	}
+	return ""
+}
+
+// CacheFilter selects cache entries to invalidate. Every non-empty field must
+// match; an empty filter matches every entry.
+type CacheFilter struct {
+	// Kind matches entries of one kind, e.g. CacheKindRouteDetails
+	Kind string
+	// Agency matches entries for one agency, e.g. DefaultAgency
+	Agency string
+	// RouteID matches route details, predictions and vehicles for one route
+	RouteID string
+	// Prefix matches keys starting with the given string, e.g. "route_details:sfmta-cis:N"
+	Prefix string
+}
+
+// matches reports whether the filter selects the given key
+func (f CacheFilter) matches(key string) bool {
+	if f.Kind != "" && cacheKind(key) != f.Kind {
+		return false
+	}
+
+	if f.Agency != "" && cacheKeyAgency(key) != f.Agency {
+		return false
+	}
+
+	if f.RouteID != "" && !strings.EqualFold(cacheKeyRouteID(key), f.RouteID) {
+		return false
+	}
+
+	return strings.HasPrefix(key, f.Prefix)
+}
+
+// Cache manages cached API responses. It is bounded by entry count and
+// approximate size, evicting the least recently used entries first, and
+// entries past their stale window are removed periodically by a background
+// janitor, started when the first entry is stored and stopped by close.
+type Cache struct {
+	ttl             time.Duration
+	kindTTLs        map[string]time.Duration
+	staleWindow     time.Duration
+	kindStaleWindow map[string]time.Duration
+	maxEntries      int
+	maxBytes        int
+	janitorInterval time.Duration
+	items           map[string]*list.Element
+	lru             *list.List
+	bytes           int
+	mutex           sync.Mutex
+	isEnabled       bool
+	stopJanitor     chan struct{}
+	closed          bool
+	disk            *diskStore
+	stats           cacheCounters
+}
+
+// newCache creates a new cache with the given default TTL
+func newCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:             ttl,
+		kindTTLs:        map[string]time.Duration{CacheKindPredictions: 0, CacheKindVehicles: 0, CacheKindAlerts: defaultAlertTTL},
+		staleWindow:     defaultCacheStaleWindow,
+		kindStaleWindow: map[string]time.Duration{CacheKindPredictions: 0, CacheKindVehicles: 0, CacheKindAlerts: 0}, // Real-time data is never served stale
+		maxEntries:      defaultCacheMaxEntries,
+		maxBytes:        defaultCacheMaxBytes,
+		janitorInterval: defaultCacheJanitorInterval,
+		items:           make(map[string]*list.Element),
+		lru:             list.New(),
+		isEnabled:       true,
+		stats:           newCacheCounters(),
+	}
+}
+
+// ttlFor returns the TTL for entries of the given kind
+func (c *Cache) ttlFor(kind string) time.Duration {
+	if ttl, ok := c.kindTTLs[kind]; ok {
+		return ttl
+	}
+	return c.ttl
+}
+
+// staleWindowFor returns how long expired entries of the given kind may be served as stale
+func (c *Cache) staleWindowFor(kind string) time.Duration {
+	if window, ok := c.kindStaleWindow[kind]; ok {
+		return window
+	}
+	return c.staleWindow
+}
+
+// get retrieves an item from the cache if it exists and is not expired
+func (c *Cache) get(key string, result interface{}) bool {
+	_, state := c.lookup(key, result)
+	return state == cacheFresh
+}
+
+// lookup retrieves an item from the cache if it exists and is still within its
+// stale window, returning when the item was stored and whether it is fresh or stale.
+// The result is only filled in if the item is found.
+func (c *Cache) lookup(key string, result interface{}) (time.Time, cacheState) {
+	return c.find(key, result, true)
+}
+
+// recheck is like lookup but doesn't count towards the cache statistics and
+// returns the encoded item. It's used to check whether another caller filled
+// the cache after a recorded miss.
+func (c *Cache) recheck(key string) (json.RawMessage, time.Time, cacheState) {
+	return c.findEncoded(key, false)
+}
+
+// find looks up an item, optionally recording the outcome in the cache
+// statistics, and decodes a private copy of it into result
+func (c *Cache) find(key string, result interface{}, record bool) (time.Time, cacheState) {
+	data, storedAt, state := c.findEncoded(key, record)
+	if state == cacheMiss {
+		return time.Time{}, cacheMiss
+	}
+
+	if err := json.Unmarshal(data, result); err != nil {
+		return time.Time{}, cacheMiss
+	}
+
+	return storedAt, state
+}
+
+// findEncoded looks up an item, optionally recording the outcome in the cache
+// statistics, and returns its encoded data
+func (c *Cache) findEncoded(key string, record bool) (json.RawMessage, time.Time, cacheState) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.isEnabled {
+		return nil, time.Time{}, cacheMiss
+	}
+
+	kind := cacheKind(key)
+
+	elem, found := c.items[key]
+	if !found {
+		if record {
+			c.stats.recordMiss(kind)
+		}
+		return nil, time.Time{}, cacheMiss
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if entry.isDead() {
+		c.evict(elem)
+		c.stats.expirations++
+		if record {
+			c.stats.recordMiss(kind)
+		}
+		return nil, time.Time{}, cacheMiss
+	}
+
+	state := cacheFresh
+	if entry.isExpired() {
+		state = cacheStale
+	}
+
+	if record {
+		c.stats.recordHit(kind, state == cacheStale)
+		entry.hits++
+	}
+
+	c.lru.MoveToFront(elem)
+
+	return entry.data, entry.storedAt, state
+}
+
+// set adds or updates an item in the cache
+func (c *Cache) set(key string, data interface{}) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	c.setEncoded(key, encoded)
+}
+
+// setEncoded adds or updates an item that has already been encoded
+func (c *Cache) setEncoded(key string, encoded json.RawMessage) {
+	kind := cacheKind(key)
+
+	c.mutex.Lock()
+	enabled := c.isEnabled
+	ttl := c.ttlFor(kind)
+	staleWindow := c.staleWindowFor(kind)
+	c.mutex.Unlock()
+
+	if !enabled || ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+	entry := &cacheEntry{
+		key:        key,
+		kind:       kind,
+		data:       encoded,
+		storedAt:   now,
+		expiration: now.Add(ttl),
+		staleUntil: now.Add(ttl + staleWindow),
+	}
+
+	c.mutex.Lock()
+
+	if elem, found := c.items[key]; found {
+		c.removeElement(elem)
+	}
+
+	// Entries larger than the whole cache are never stored
+	if c.maxBytes > 0 && entry.size() > c.maxBytes {
+		c.removeFromDisk(key)
+		c.mutex.Unlock()
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(entry)
+	c.bytes += entry.size()
+	c.startJanitor()
+
+	// Write through to disk under the lock, so a concurrent clear or
+	// invalidation can't be undone by a late write
+	if c.disk != nil {
+		if err := c.disk.save(entry); err != nil {
+			log.Printf("Error writing cache entry %s to disk: %v", key, err)
+		}
+	}
+
+	c.evictOverflow()
+	c.mutex.Unlock()
+}
+
+// evictOverflow removes least recently used entries until the cache fits its
+// limits. Caller must hold the mutex.
+func (c *Cache) evictOverflow() {
+	for c.lru.Len() > 0 && c.overLimit() {
+		c.evict(c.lru.Back())
+		c.stats.evictions++
+	}
+}
+
+// evict removes an entry from memory and disk. Caller must hold the mutex.
+func (c *Cache) evict(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	c.removeElement(elem)
+	c.removeFromDisk(entry.key)
+}
+
+// removeFromDisk deletes an entry's file if the cache is persisted. Caller must hold the mutex.
+func (c *Cache) removeFromDisk(key string) {
+	if c.disk == nil {
+		return
+	}
+
+	if err := c.disk.remove(key); err != nil {
+		log.Printf("Error removing cache entry %s from disk: %v", key, err)
+	}
+}
+
+// overLimit reports whether the cache exceeds its limits. Caller must hold the mutex.
+func (c *Cache) overLimit() bool {
+	return (c.maxEntries > 0 && c.lru.Len() > c.maxEntries) ||
+		(c.maxBytes > 0 && c.bytes > c.maxBytes)
+}
+
+// removeElement removes an entry from the cache. Caller must hold the mutex.
+func (c *Cache) removeElement(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.items, entry.key)
+	c.bytes -= entry.size()
+}
+
+// removeExpired removes all entries past their stale window from the cache
+func (c *Cache) removeExpired() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for elem := c.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if elem.Value.(*cacheEntry).isDead() {
+			c.evict(elem)
+			c.stats.expirations++
+		}
+		elem = prev
+	}
+}
+
+// startJanitor starts a background goroutine that periodically removes
+// expired entries, unless it's already running or the cache is closed. Caller
+// must hold the mutex.
+func (c *Cache) startJanitor() {
+	if c.janitorInterval <= 0 || c.stopJanitor != nil || c.closed {
+		return
+	}
+
+	c.stopJanitor = make(chan struct{})
+	go func(interval time.Duration, stop <-chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.removeExpired()
+			case <-stop:
+				return
+			}
+		}
+	}(c.janitorInterval, c.stopJanitor)
+}
+
+// close stops the background janitor. It's safe to call more than once.
+func (c *Cache) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+	if c.stopJanitor != nil {
+		close(c.stopJanitor)
+		c.stopJanitor = nil
+	}
+}
+
+// clear removes all items from the cache
+func (c *Cache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
+	c.bytes = 0
+
+	if c.disk != nil {
+		if err := c.disk.clear(); err != nil {
+			log.Printf("Error clearing cache directory: %v", err)
+		}
+	}
+}
+
+// invalidate removes the entries matching the filter from memory and disk,
+// returning how many were removed
+func (c *Cache) invalidate(filter CacheFilter) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	removed := 0
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if filter.matches(elem.Value.(*cacheEntry).key) {
+			c.evict(elem)
+			removed++
+		}
+		elem = next
+	}
+
+	return removed
+}
+
+// loadFromDisk fills the cache with the entries persisted on disk
+func (c *Cache) loadFromDisk() {
+	if c.disk == nil {
+		return
+	}
+
+	entries, err := c.disk.load()
+	if err != nil {
+		log.Printf("Error loading cache from disk: %v", err)
+		return
+	}
+
+	// Insert oldest first so the most recently stored entries are the last to be evicted
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].storedAt.Before(entries[j].storedAt)
+	})
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, entry := range entries {
+		if elem, found := c.items[entry.key]; found {
+			c.removeElement(elem)
+		}
+
+		c.items[entry.key] = c.lru.PushFront(entry)
+		c.bytes += entry.size()
+	}
+
+	if len(entries) > 0 {
+		c.startJanitor()
+	}
+
+	c.evictOverflow()
+}
+
+// enable turns on caching
+func (c *Cache) enable() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.isEnabled = true
+}
+
+// disable turns off caching
+func (c *Cache) disable() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.isEnabled = false
+}
@@ -0,0 +1,443 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newCache(time.Minute)
+	cache.maxEntries = 2
+
+	cache.set("a", "1")
+	cache.set("b", "2")
+
+	// Touch "a" so "b" becomes the least recently used entry
+	var result string
+	if !cache.get("a", &result) {
+		t.Fatal("Expected a to be cached")
+	}
+
+	cache.set("c", "3")
+
+	if cache.get("b", &result) {
+		t.Error("Expected b to be evicted")
+	}
+
+	if !cache.get("a", &result) || result != "1" {
+		t.Errorf("Expected a to be cached with value 1, got %q", result)
+	}
+
+	if !cache.get("c", &result) || result != "3" {
+		t.Errorf("Expected c to be cached with value 3, got %q", result)
+	}
+}
+
+func TestCacheEvictsBySize(t *testing.T) {
+	cache := newCache(time.Minute)
+	cache.maxBytes = 20
+
+	cache.set("a", "0123456789")
+	cache.set("b", "0123456789")
+
+	var result string
+	if cache.get("a", &result) {
+		t.Error("Expected a to be evicted to stay under the size limit")
+	}
+
+	if !cache.get("b", &result) {
+		t.Error("Expected b to be cached")
+	}
+
+	if cache.bytes > cache.maxBytes {
+		t.Errorf("Expected cache size to be at most %d, got %d", cache.maxBytes, cache.bytes)
+	}
+}
+
+func TestCacheKindTTLs(t *testing.T) {
+	client := NewClient("http://test.com", WithCacheTTL(time.Hour), WithRouteTTL(time.Millisecond))
+	defer client.Close()
+
+	client.cache.set("route_details:sfmta-cis:N", "route")
+	client.cache.set("other", "data")
+
+	time.Sleep(5 * time.Millisecond)
+
+	var result string
+	if client.cache.get("route_details:sfmta-cis:N", &result) {
+		t.Error("Expected route details to expire using the route TTL")
+	}
+
+	if !client.cache.get("other", &result) {
+		t.Error("Expected other data to use the default TTL")
+	}
+}
+
+func TestCacheRemoveExpired(t *testing.T) {
+	cache := newCache(time.Millisecond)
+	cache.staleWindow = 0
+	cache.set("a", "1")
+	cache.kindTTLs["b"] = time.Hour
+	cache.set("b", "2")
+
+	time.Sleep(5 * time.Millisecond)
+	cache.removeExpired()
+
+	if _, found := cache.items["a"]; found {
+		t.Error("Expected expired entry to be removed")
+	}
+
+	if _, found := cache.items["b"]; !found {
+		t.Error("Expected live entry to be kept")
+	}
+}
+
+func TestCacheJanitor(t *testing.T) {
+	client := NewClient("http://test.com", WithCacheTTL(time.Millisecond), WithStaleWindow(0), WithCacheJanitorInterval(5*time.Millisecond))
+	defer client.Close()
+
+	client.cache.set("a", "1")
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		client.cache.mutex.Lock()
+		remaining := client.cache.lru.Len()
+		client.cache.mutex.Unlock()
+
+		if remaining == 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Error("Expected janitor to remove the expired entry")
+}
+
+func TestCacheJanitorStartsOnFirstEntry(t *testing.T) {
+	client := NewClient("http://test.com")
+	defer client.Close()
+
+	janitorRunning := func() bool {
+		client.cache.mutex.Lock()
+		defer client.cache.mutex.Unlock()
+		return client.cache.stopJanitor != nil
+	}
+
+	if janitorRunning() {
+		t.Error("Expected no janitor before anything is cached")
+	}
+
+	client.cache.set("a", "1")
+	if !janitorRunning() {
+		t.Error("Expected janitor to start once an entry is cached")
+	}
+
+	// Close is safe to call concurrently and more than once
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.Close()
+		}()
+	}
+	wg.Wait()
+
+	client.cache.set("b", "2")
+	if janitorRunning() {
+		t.Error("Expected janitor not to restart after close")
+	}
+}
+
+func TestPredictionCachingIsOptIn(t *testing.T) {
+	var calls int32
+	server := slowServer(mockPredictionsResponse, 0, &calls)
+	defer server.Close()
+
+	// Predictions bypass the cache by default
+	client := NewClient(server.URL)
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetPredictions(context.Background(), "N", "1234"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("Expected 2 upstream requests without prediction caching, got %d", got)
+	}
+
+	// With a prediction TTL repeated requests are served from the cache
+	atomic.StoreInt32(&calls, 0)
+	client = NewClient(server.URL, WithPredictionTTL(time.Minute))
+	defer client.Close()
+
+	first, err := client.GetPredictions(context.Background(), "N", "1234")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	second, err := client.GetPredictions(context.Background(), "N", "1234")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected 1 upstream request with prediction caching, got %d", got)
+	}
+
+	if second[0].FetchedAt.Sub(first[0].FetchedAt).Abs() > time.Second {
+		t.Errorf("Expected cached predictions to keep their fetch time, got %v and %v", first[0].FetchedAt, second[0].FetchedAt)
+	}
+
+	if second[0].AgeSeconds < 0 {
+		t.Errorf("Expected non-negative data age, got %d", second[0].AgeSeconds)
+	}
+}
+
+func TestStaleRoutesServedOnUpstreamError(t *testing.T) {
+	var failing int32
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(mockRouteDetailsResponse))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRouteTTL(time.Millisecond), WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	details, err := client.GetRouteDetails(context.Background(), "N")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if details.Stale {
+		t.Error("Expected freshly fetched details not to be stale")
+	}
+
+	// Let the entry expire and take the upstream down
+	time.Sleep(5 * time.Millisecond)
+	atomic.StoreInt32(&failing, 1)
+
+	details, err = client.GetRouteDetails(context.Background(), "N")
+	if err != nil {
+		t.Fatalf("Expected stale details instead of an error, got %v", err)
+	}
+
+	if !details.Stale {
+		t.Error("Expected expired details to be flagged as stale")
+	}
+
+	if details.ID != "N" {
+		t.Errorf("Expected route ID to be N, got %s", details.ID)
+	}
+
+	// The background refresh fails but the stale entry is kept
+	waitForCalls(t, &calls, 2)
+
+	if _, err := client.GetRouteDetails(context.Background(), "N"); err != nil {
+		t.Errorf("Expected stale details to keep being served, got %v", err)
+	}
+}
+
+func TestStaleRoutesRefreshedInBackground(t *testing.T) {
+	var calls int32
+	server := slowServer(mockRoutesResponse, 0, &calls)
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRouteTTL(20*time.Millisecond))
+	defer client.Close()
+
+	if _, err := client.GetAllRoutes(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	routes, err := client.GetAllRoutes(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !routes[0].Stale {
+		t.Error("Expected expired routes to be flagged as stale")
+	}
+
+	waitForCalls(t, &calls, 2)
+
+	// Wait for the refreshed value to land in the cache
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		routes, err = client.GetAllRoutes(context.Background())
+		if err == nil && !routes[0].Stale {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Error("Expected background refresh to replace the stale routes")
+}
+
+func TestCacheMissJoinsBackgroundRefresh(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hold the background refresh until the cache has been cleared
+		if atomic.AddInt32(&calls, 1) == 2 {
+			<-release
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(mockRoutesResponse))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRouteTTL(time.Millisecond))
+	defer client.Close()
+
+	if _, err := client.GetAllRoutes(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Serve the expired routes as stale, starting a background refresh
+	time.Sleep(5 * time.Millisecond)
+	if _, err := client.GetAllRoutes(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	waitForCalls(t, &calls, 2)
+
+	// A miss while the refresh is in flight joins it
+	client.ClearCache()
+
+	done := make(chan error, 1)
+	go func() {
+		routes, err := client.GetAllRoutes(context.Background())
+		if err == nil && len(routes) == 0 {
+			err = errors.New("no routes returned")
+		}
+		done <- err
+	}()
+
+	// Give the caller time to join the in-flight refresh
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for routes")
+	}
+}
+
+// waitForCalls waits until the counter reaches at least n
+func waitForCalls(t *testing.T, calls *int32, n int32) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if atomic.LoadInt32(calls) >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("Expected at least %d upstream requests, got %d", n, atomic.LoadInt32(calls))
+}
+
+func TestCacheStats(t *testing.T) {
+	client := NewClient("http://test.com", WithCacheMaxEntries(1))
+	defer client.Close()
+
+	client.cache.set("route_details:N", "n")
+
+	var result string
+	client.cache.get("route_details:N", &result)
+	client.cache.get("route_details:N", &result)
+	client.cache.get("route_details:J", &result)
+
+	// Evicts route_details:N
+	client.cache.set("all_routes", "routes")
+
+	stats := client.CacheStats()
+
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %d hits and %d misses", stats.Hits, stats.Misses)
+	}
+
+	if stats.Evictions != 1 {
+		t.Errorf("Expected 1 eviction, got %d", stats.Evictions)
+	}
+
+	kind := stats.Kinds["route_details"]
+	if kind.HitRatio < 0.66 || kind.HitRatio > 0.67 {
+		t.Errorf("Expected route_details hit ratio of 2/3, got %v", kind.HitRatio)
+	}
+
+	if len(stats.Keys) != 1 || stats.Keys[0].Key != "all_routes" {
+		t.Fatalf("Expected only all_routes to be cached, got %+v", stats.Keys)
+	}
+
+	if stats.Keys[0].ExpiresInSeconds <= 0 {
+		t.Errorf("Expected all_routes to expire in the future, got %d", stats.Keys[0].ExpiresInSeconds)
+	}
+}
+
+func TestInvalidateCache(t *testing.T) {
+	client := NewClient("http://test.com", WithPredictionTTL(time.Minute))
+	defer client.Close()
+
+	keys := []string{"all_routes:sfmta-cis", "route_details:sfmta-cis:N", "route_details:sfmta-cis:J", "predictions:sfmta-cis:N:1234", "predictions:sfmta-cis:J:5678", "route_details:other:N"}
+
+	tests := []struct {
+		name      string
+		filter    CacheFilter
+		remaining []string
+	}{
+		{"by route", CacheFilter{RouteID: "N"}, []string{"all_routes:sfmta-cis", "predictions:sfmta-cis:J:5678", "route_details:sfmta-cis:J"}},
+		{"by kind", CacheFilter{Kind: CacheKindPredictions}, []string{"all_routes:sfmta-cis", "route_details:other:N", "route_details:sfmta-cis:J", "route_details:sfmta-cis:N"}},
+		{"by prefix", CacheFilter{Prefix: "route_details:sfmta-cis:"}, []string{"all_routes:sfmta-cis", "predictions:sfmta-cis:J:5678", "predictions:sfmta-cis:N:1234", "route_details:other:N"}},
+		{"by kind and route", CacheFilter{Kind: CacheKindRouteDetails, RouteID: "J"}, []string{"all_routes:sfmta-cis", "predictions:sfmta-cis:J:5678", "predictions:sfmta-cis:N:1234", "route_details:other:N", "route_details:sfmta-cis:N"}},
+		{"by agency", CacheFilter{Agency: "other"}, []string{"all_routes:sfmta-cis", "predictions:sfmta-cis:J:5678", "predictions:sfmta-cis:N:1234", "route_details:sfmta-cis:J", "route_details:sfmta-cis:N"}},
+		{"everything", CacheFilter{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.ClearCache()
+			for _, key := range keys {
+				client.cache.set(key, "data")
+			}
+
+			removed := client.InvalidateCache(tt.filter)
+			if removed != len(keys)-len(tt.remaining) {
+				t.Errorf("Expected %d entries removed, got %d", len(keys)-len(tt.remaining), removed)
+			}
+
+			stats := client.CacheStats()
+			if len(stats.Keys) != len(tt.remaining) {
+				t.Fatalf("Expected %d remaining entries, got %+v", len(tt.remaining), stats.Keys)
+			}
+
+			for i, key := range tt.remaining {
+				if stats.Keys[i].Key != key {
+					t.Errorf("Expected remaining key %s, got %s", key, stats.Keys[i].Key)
+				}
+			}
+		})
+	}
+}
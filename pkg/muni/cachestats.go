@@ -0,0 +1,164 @@
+package muni
+
+import (
+	"sort"
+	"time"
+)
+
+// CacheStats is a snapshot of cache usage
+type CacheStats struct {
+	Enabled     bool                      `json:"enabled"`
+	Entries     int                       `json:"entries"`
+	Bytes       int                       `json:"bytes"`
+	MaxEntries  int                       `json:"max_entries"`
+	MaxBytes    int                       `json:"max_bytes"`
+	Hits        int64                     `json:"hits"`
+	StaleHits   int64                     `json:"stale_hits"`
+	Misses      int64                     `json:"misses"`
+	Evictions   int64                     `json:"evictions"`
+	Expirations int64                     `json:"expirations"`
+	HitRatio    float64                   `json:"hit_ratio"`
+	Kinds       map[string]CacheKindStats `json:"kinds"`
+	Keys        []CacheKeyStats           `json:"keys"`
+}
+
+// CacheKindStats summarizes cache usage for one kind of data (e.g. route_details)
+type CacheKindStats struct {
+	Entries   int     `json:"entries"`
+	Bytes     int     `json:"bytes"`
+	Hits      int64   `json:"hits"`
+	StaleHits int64   `json:"stale_hits"`
+	Misses    int64   `json:"misses"`
+	HitRatio  float64 `json:"hit_ratio"`
+}
+
+// CacheKeyStats describes a single cached entry
+type CacheKeyStats struct {
+	Key              string    `json:"key"`
+	Kind             string    `json:"kind"`
+	Bytes            int       `json:"bytes"`
+	Hits             int64     `json:"hits"`
+	StoredAt         time.Time `json:"stored_at"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	AgeSeconds       int       `json:"age_seconds"`
+	ExpiresInSeconds int       `json:"expires_in_seconds"`
+	Stale            bool      `json:"stale"`
+}
+
+// hitCounters counts lookups for one kind of data
+type hitCounters struct {
+	hits      int64
+	staleHits int64
+	misses    int64
+}
+
+// cacheCounters tracks cache usage. Access must hold the cache mutex.
+type cacheCounters struct {
+	hitCounters
+	evictions   int64
+	expirations int64
+	kinds       map[string]*hitCounters
+}
+
+// newCacheCounters creates zeroed cache counters
+func newCacheCounters() cacheCounters {
+	return cacheCounters{
+		kinds: make(map[string]*hitCounters),
+	}
+}
+
+// kind returns the counters for a kind of data, creating them if needed
+func (s *cacheCounters) kind(kind string) *hitCounters {
+	counters, ok := s.kinds[kind]
+	if !ok {
+		counters = &hitCounters{}
+		s.kinds[kind] = counters
+	}
+	return counters
+}
+
+// recordHit counts a fresh or stale cache hit
+func (s *cacheCounters) recordHit(kind string, stale bool) {
+	counters := s.kind(kind)
+	if stale {
+		s.staleHits++
+		counters.staleHits++
+	} else {
+		s.hits++
+		counters.hits++
+	}
+}
+
+// recordMiss counts a cache miss
+func (s *cacheCounters) recordMiss(kind string) {
+	s.misses++
+	s.kind(kind).misses++
+}
+
+// hitRatio returns the fraction of lookups served from the cache, counting stale hits
+func hitRatio(counters hitCounters) float64 {
+	total := counters.hits + counters.staleHits + counters.misses
+	if total == 0 {
+		return 0
+	}
+	return float64(counters.hits+counters.staleHits) / float64(total)
+}
+
+// snapshot returns the current cache statistics, with keys sorted alphabetically
+func (c *Cache) snapshot() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	stats := CacheStats{
+		Enabled:     c.isEnabled,
+		Entries:     c.lru.Len(),
+		Bytes:       c.bytes,
+		MaxEntries:  c.maxEntries,
+		MaxBytes:    c.maxBytes,
+		Hits:        c.stats.hits,
+		StaleHits:   c.stats.staleHits,
+		Misses:      c.stats.misses,
+		Evictions:   c.stats.evictions,
+		Expirations: c.stats.expirations,
+		HitRatio:    hitRatio(c.stats.hitCounters),
+		Kinds:       make(map[string]CacheKindStats),
+		Keys:        make([]CacheKeyStats, 0, c.lru.Len()),
+	}
+
+	for kind, counters := range c.stats.kinds {
+		stats.Kinds[kind] = CacheKindStats{
+			Hits:      counters.hits,
+			StaleHits: counters.staleHits,
+			Misses:    counters.misses,
+			HitRatio:  hitRatio(*counters),
+		}
+	}
+
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*cacheEntry)
+
+		kindStats := stats.Kinds[entry.kind]
+		kindStats.Entries++
+		kindStats.Bytes += entry.size()
+		stats.Kinds[entry.kind] = kindStats
+
+		stats.Keys = append(stats.Keys, CacheKeyStats{
+			Key:              entry.key,
+			Kind:             entry.kind,
+			Bytes:            entry.size(),
+			Hits:             entry.hits,
+			StoredAt:         entry.storedAt,
+			ExpiresAt:        entry.expiration,
+			AgeSeconds:       int(now.Sub(entry.storedAt).Seconds()),
+			ExpiresInSeconds: int(entry.expiration.Sub(now).Seconds()),
+			Stale:            now.After(entry.expiration),
+		})
+	}
+
+	sort.Slice(stats.Keys, func(i, j int) bool {
+		return stats.Keys[i].Key < stats.Keys[j].Key
+	})
+
+	return stats
+}
@@ -8,7 +8,8 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"sync"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,114 +17,214 @@ import (
 var (
 	ErrRouteIDRequired = errors.New("route ID is required")
 	ErrStopIDRequired  = errors.New("stop ID is required")
+	ErrStopNotFound    = errors.New("no route serves stop")
 )
 
-// cacheEntry represents a cached item with expiration
-type cacheEntry struct {
-	data       interface{}
-	expiration time.Time
+// Endpoint names identify which upstream API an error or request belongs to
+const (
+	EndpointRoutes       = "routes"
+	EndpointRouteDetails = "route_details"
+	EndpointPredictions  = "predictions"
+	EndpointVehicles     = "vehicles"
+	EndpointAlerts       = "alerts"
+)
+
+// DefaultPredictionStaleAfter is how old prediction data may get before it is flagged stale
+const DefaultPredictionStaleAfter = 2 * time.Minute
+
+// maxErrorBodySize limits how much of an error response body is kept in an APIError
+const maxErrorBodySize = 512
+
+// APIError is returned when the MUNI API responds with a non-200 status code
+type APIError struct {
+	StatusCode int
+	Endpoint   string
+	URL        string
+	RetryAfter time.Duration
+	Body       string
 }
 
-// isExpired checks if the cache entry has expired
-func (c *cacheEntry) isExpired() bool {
-	return time.Now().After(c.expiration)
+// Error implements the error interface
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("unexpected status code: %d from %s endpoint", e.StatusCode, e.Endpoint)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
 }
 
-// Cache manages cached API responses
-type Cache struct {
-	ttl       time.Duration
-	items     map[string]cacheEntry
-	mutex     sync.RWMutex
-	isEnabled bool
+// Temporary reports whether the request may succeed if retried
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
 }
 
-// newCache creates a new cache with the given TTL
-func newCache(ttl time.Duration) *Cache {
-	return &Cache{
-		ttl:       ttl,
-		items:     make(map[string]cacheEntry),
-		isEnabled: true,
+// newAPIError builds an APIError from an unsuccessful response
+func newAPIError(endpoint, url string, resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Endpoint:   endpoint,
+		URL:        url,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		Body:       strings.TrimSpace(string(body)),
 	}
 }
 
-// get retrieves an item from the cache if it exists and is not expired
-func (c *Cache) get(key string, result interface{}) bool {
-	if !c.isEnabled {
-		return false
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
 	}
 
-	c.mutex.RLock()
-	entry, found := c.items[key]
-	c.mutex.RUnlock()
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
 
-	if !found || entry.isExpired() {
-		return false
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
 	}
 
-	// Copy the cached data to the result
-	data, err := json.Marshal(entry.data)
-	if err != nil {
-		return false
+	return 0
+}
+
+// Client represents a client for the SF MUNI API
+type Client struct {
+	baseURL     string
+	agency      string
+	httpClient  *http.Client
+	cache       *Cache
+	retryPolicy RetryPolicy
+	limiter     *rateLimiter
+	flights     *flightGroup
+	stops       *stopIndexes
+
+	predictionStaleAfter time.Duration
+}
+
+// ClientOption is a functional option for configuring the client
+type ClientOption func(*Client)
+
+// WithAgency sets the umoiq agency the client queries by default. Individual
+// requests can use another agency with ContextWithAgency.
+func WithAgency(agencyID string) ClientOption {
+	return func(c *Client) {
+		c.agency = agencyID
 	}
+}
 
-	if err := json.Unmarshal(data, result); err != nil {
-		return false
+// WithCacheTTL sets the default cache time-to-live duration
+func WithCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.cache.ttl = ttl
 	}
+}
 
-	return true
+// WithRouteTTL sets the cache time-to-live for the route list and route details,
+// overriding the default cache TTL
+func WithRouteTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.cache.kindTTLs[CacheKindAllRoutes] = ttl
+		c.cache.kindTTLs[CacheKindRouteDetails] = ttl
+	}
 }
 
-// set adds or updates an item in the cache
-func (c *Cache) set(key string, data interface{}) {
-	if !c.isEnabled {
-		return
+// WithPredictionTTL enables caching of predictions for the given duration.
+// Predictions are not cached by default; keep this to a few seconds.
+func WithPredictionTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.cache.kindTTLs[CacheKindPredictions] = ttl
 	}
+}
 
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// WithVehicleTTL enables caching of vehicle locations for the given duration.
+// Vehicle locations are not cached by default; keep this to a few seconds.
+func WithVehicleTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.cache.kindTTLs[CacheKindVehicles] = ttl
+	}
+}
 
-	c.items[key] = cacheEntry{
-		data:       data,
-		expiration: time.Now().Add(c.ttl),
+// WithAlertTTL sets how long service alerts are cached. Defaults to one minute.
+func WithAlertTTL(ttl time.Duration) ClientOption {
+	return func(c *Client) {
+		c.cache.kindTTLs[CacheKindAlerts] = ttl
 	}
 }
 
-// clear removes all items from the cache
-func (c *Cache) clear() {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// WithPredictionStaleAfter sets how old prediction data may get before the
+// predictions built from it are flagged stale
+func WithPredictionStaleAfter(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.predictionStaleAfter = d
+	}
+}
 
-	c.items = make(map[string]cacheEntry)
+// WithStaleWindow sets how long expired route data may still be served as
+// stale while it is refreshed, or while the API is unavailable. Predictions
+// and vehicle locations are never served stale.
+func WithStaleWindow(window time.Duration) ClientOption {
+	return func(c *Client) {
+		c.cache.staleWindow = window
+	}
 }
 
-// enable turns on caching
-func (c *Cache) enable() {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	c.isEnabled = true
+// WithCacheMaxEntries limits the number of cached responses. Zero means unlimited.
+func WithCacheMaxEntries(n int) ClientOption {
+	return func(c *Client) {
+		c.cache.maxEntries = n
+	}
 }
 
-// disable turns off caching
-func (c *Cache) disable() {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	c.isEnabled = false
+// WithCacheMaxBytes limits the approximate size of the cache in bytes. Zero means unlimited.
+func WithCacheMaxBytes(n int) ClientOption {
+	return func(c *Client) {
+		c.cache.maxBytes = n
+	}
 }
 
-// Client represents a client for the SF MUNI API
-type Client struct {
-	baseURL    string
-	httpClient *http.Client
-	cache      *Cache
+// WithCacheJanitorInterval sets how often expired cache entries are removed.
+// Zero disables the background janitor.
+func WithCacheJanitorInterval(interval time.Duration) ClientOption {
+	return func(c *Client) {
+		c.cache.janitorInterval = interval
+	}
 }
 
-// ClientOption is a functional option for configuring the client
-type ClientOption func(*Client)
+// WithCacheDir persists the cache as JSON files in dir so it survives restarts.
+// Entries are loaded when the client is created and written through on every update.
+func WithCacheDir(dir string) ClientOption {
+	return func(c *Client) {
+		disk, err := newDiskStore(dir)
+		if err != nil {
+			log.Printf("Error creating cache directory %s, using in-memory cache only: %v", dir, err)
+			return
+		}
+		c.cache.disk = disk
+	}
+}
 
-// WithCacheTTL sets the cache time-to-live duration
-func WithCacheTTL(ttl time.Duration) ClientOption {
+// WithRetryPolicy sets the policy used to retry failed API requests
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithRateLimit limits the total rate of requests made to the API across all endpoints
+func WithRateLimit(limit RateLimit) ClientOption {
+	return func(c *Client) {
+		c.limiter.global = newTokenBucket(limit)
+	}
+}
+
+// WithEndpointRateLimit limits the rate of requests made to a single endpoint
+// (e.g. EndpointPredictions) in addition to any client-wide limit
+func WithEndpointRateLimit(endpoint string, limit RateLimit) ClientOption {
 	return func(c *Client) {
-		c.cache = newCache(ttl)
+		c.limiter.endpoints[endpoint] = newTokenBucket(limit)
 	}
 }
 
@@ -137,9 +238,16 @@ func WithoutCache() ClientOption {
 // NewClient creates a new MUNI API client
 func NewClient(baseURL string, opts ...ClientOption) *Client {
 	c := &Client{
-		baseURL:    baseURL,
-		httpClient: &http.Client{},
-		cache:      newCache(5 * time.Minute), // Default cache TTL
+		baseURL:     baseURL,
+		agency:      DefaultAgency,
+		httpClient:  &http.Client{},
+		cache:       newCache(5 * time.Minute), // Default cache TTL
+		retryPolicy: DefaultRetryPolicy(),
+		limiter:     newRateLimiter(),
+		flights:     newFlightGroup(),
+		stops:       newStopIndexes(),
+
+		predictionStaleAfter: DefaultPredictionStaleAfter,
 	}
 
 	// Apply options
@@ -147,12 +255,28 @@ func NewClient(baseURL string, opts ...ClientOption) *Client {
 		opt(c)
 	}
 
+	c.cache.loadFromDisk()
+
 	return c
 }
 
+// Close stops the client's background cache maintenance, which starts once
+// something is cached. The client remains usable, and Close may be called
+// more than once.
+func (c *Client) Close() {
+	c.cache.close()
+}
+
 // ClearCache clears all cached responses
 func (c *Client) ClearCache() {
 	c.cache.clear()
+	c.stops.clear()
+}
+
+// InvalidateCache removes the cached responses matching the filter, returning
+// how many were removed. Unlike ClearCache, other entries stay warm.
+func (c *Client) InvalidateCache(filter CacheFilter) int {
+	return c.cache.invalidate(filter)
 }
 
 // EnableCache enables caching
@@ -165,6 +289,11 @@ func (c *Client) DisableCache() {
 	c.cache.disable()
 }
 
+// CacheStats returns a snapshot of cache usage and the entries currently cached
+func (c *Client) CacheStats() CacheStats {
+	return c.cache.snapshot()
+}
+
 // Add helper function at the top of the file after package declaration
 func closeBody(body io.ReadCloser) {
 	if err := body.Close(); err != nil {
@@ -172,89 +301,197 @@ func closeBody(body io.ReadCloser) {
 	}
 }
 
-// GetAllRoutes fetches all available MUNI routes with detailed information
-func (c *Client) GetAllRoutes(ctx context.Context) ([]RouteInfo, error) {
-	cacheKey := "all_routes"
-
-	// Try to get from cache first
-	var routes []RouteInfo
-	if c.cache.get(cacheKey, &routes) {
-		return routes, nil
-	}
-
-	url := fmt.Sprintf("%s/v2.0/riders/agencies/sfmta-cis/routes", c.baseURL)
+// fetchJSON performs a GET request and decodes the JSON response into result,
+// retrying transient failures according to the client's retry policy.
+// Every attempt counts against the client's rate limits.
+func (c *Client) fetchJSON(ctx context.Context, endpoint, url string, result interface{}) error {
+	return c.retryPolicy.do(ctx, func() error {
+		if err := c.limiter.wait(ctx, endpoint); err != nil {
+			return err
+		}
+		return c.fetchOnce(ctx, endpoint, url, result)
+	})
+}
 
+// fetchOnce performs a single GET request and decodes the JSON response into result
+func (c *Client) fetchOnce(ctx context.Context, endpoint, url string, result interface{}) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	req.Header.Set("Accept", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer closeBody(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return newAPIError(endpoint, url, resp)
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&routes); err != nil {
-		return nil, err
-	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
 
-	// Cache the response
-	c.cache.set(cacheKey, routes)
+// refreshTimeout bounds background refreshes of stale cache entries
+const refreshTimeout = 30 * time.Second
 
-	return routes, nil
+// cachedValue is a value along with when it was fetched from the API
+type cachedValue[T any] struct {
+	value     T
+	fetchedAt time.Time
+	stale     bool
 }
 
-// GetRouteDetails fetches detailed information for a specific route
-func (c *Client) GetRouteDetails(ctx context.Context, routeID string) (*RouteDetails, error) {
-	if routeID == "" {
-		return nil, ErrRouteIDRequired
+// sharedFetch is a fetched value shared between concurrent callers. It's kept
+// encoded so that each caller decodes a private copy it's free to modify.
+type sharedFetch struct {
+	data      json.RawMessage
+	fetchedAt time.Time
+}
+
+// fetchCached returns the value cached under key, calling fetch on a miss.
+// Concurrent misses share a single fetch. Expired entries still within their
+// stale window are returned immediately, flagged as stale, while a refresh runs
+// in the background; if the refresh fails the stale value keeps being served.
+// Every caller gets its own copy of the value.
+func fetchCached[T any](ctx context.Context, c *Client, key string, fetch func(ctx context.Context) (T, error)) (cachedValue[T], error) {
+	var cached T
+	storedAt, state := c.cache.lookup(key, &cached)
+	switch state {
+	case cacheFresh:
+		return cachedValue[T]{value: cached, fetchedAt: storedAt}, nil
+	case cacheStale:
+		go refreshCached(c, key, fetch)
+		return cachedValue[T]{value: cached, fetchedAt: storedAt, stale: true}, nil
+	}
+
+	// Share a single upstream request between concurrent callers
+	result, err := c.flights.do(ctx, key, func() (interface{}, error) {
+		// Another caller may have filled the cache since we checked
+		if data, storedAt, state := c.cache.recheck(key); state == cacheFresh {
+			return sharedFetch{data: data, fetchedAt: storedAt}, nil
+		}
+
+		return fetchShared(ctx, c, key, fetch)
+	})
+	if err != nil {
+		return cachedValue[T]{}, err
 	}
 
-	cacheKey := fmt.Sprintf("route_details:%s", routeID)
+	shared := result.(sharedFetch)
 
-	// Try to get from cache first
-	var routeDetails RouteDetails
-	if c.cache.get(cacheKey, &routeDetails) {
-		return &routeDetails, nil
+	var value T
+	if err := json.Unmarshal(shared.data, &value); err != nil {
+		return cachedValue[T]{}, err
 	}
 
-	url := fmt.Sprintf("%s/v2.0/riders/agencies/sfmta-cis/routes/%s", c.baseURL, routeID)
+	return cachedValue[T]{value: value, fetchedAt: shared.fetchedAt}, nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// fetchShared fetches a value and caches it, encoding it once for every
+// caller sharing the fetch
+func fetchShared[T any](ctx context.Context, c *Client, key string, fetch func(ctx context.Context) (T, error)) (sharedFetch, error) {
+	value, err := fetch(ctx)
 	if err != nil {
-		return nil, err
+		return sharedFetch{}, err
 	}
 
-	req.Header.Set("Accept", "application/json")
+	data, err := json.Marshal(value)
+	if err != nil {
+		return sharedFetch{}, err
+	}
 
-	resp, err := c.httpClient.Do(req)
+	// Cache the response
+	c.cache.setEncoded(key, data)
+
+	return sharedFetch{data: data, fetchedAt: time.Now()}, nil
+}
+
+// refreshCached fetches a fresh value for a stale cache entry, sharing the
+// request with any concurrent callers. Failures are logged and the stale entry
+// is kept. Callers that miss the cache while the refresh runs join it, so it
+// returns the same sharedFetch as fetchCached.
+func refreshCached[T any](c *Client, key string, fetch func(ctx context.Context) (T, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+
+	_, err := c.flights.do(ctx, key, func() (interface{}, error) {
+		return fetchShared(ctx, c, key, fetch)
+	})
+	if err != nil {
+		log.Printf("Error refreshing stale cache entry %s: %v", key, err)
+	}
+}
+
+// GetAllRoutes fetches all available MUNI routes with detailed information.
+// If the API is unavailable, recently expired routes are returned flagged as stale.
+func (c *Client) GetAllRoutes(ctx context.Context) ([]RouteInfo, error) {
+	agency := c.agencyFor(ctx)
+	cacheKey := buildCacheKey(CacheKindAllRoutes, agency)
+
+	result, err := fetchCached(ctx, c, cacheKey, func(ctx context.Context) ([]RouteInfo, error) {
+		url := fmt.Sprintf("%s/v2.0/riders/agencies/%s/routes", c.baseURL, agency)
+
+		var routes []RouteInfo
+		if err := c.fetchJSON(ctx, EndpointRoutes, url, &routes); err != nil {
+			return nil, err
+		}
+
+		return routes, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer closeBody(resp.Body)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	routes := result.value
+	if result.stale {
+		// The value is a private copy decoded from the cache, so it's safe to flag
+		for i := range routes {
+			routes[i].Stale = true
+		}
+	}
+
+	return routes, nil
+}
+
+// GetRouteDetails fetches detailed information for a specific route.
+// If the API is unavailable, recently expired details are returned flagged as stale.
+func (c *Client) GetRouteDetails(ctx context.Context, routeID string) (*RouteDetails, error) {
+	if routeID == "" {
+		return nil, ErrRouteIDRequired
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&routeDetails); err != nil {
+	agency := c.agencyFor(ctx)
+	cacheKey := buildCacheKey(CacheKindRouteDetails, agency, routeID)
+
+	result, err := fetchCached(ctx, c, cacheKey, func(ctx context.Context) (*RouteDetails, error) {
+		url := fmt.Sprintf("%s/v2.0/riders/agencies/%s/routes/%s", c.baseURL, agency, routeID)
+
+		var routeDetails RouteDetails
+		if err := c.fetchJSON(ctx, EndpointRouteDetails, url, &routeDetails); err != nil {
+			return nil, err
+		}
+
+		return &routeDetails, nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Cache the response
-	c.cache.set(cacheKey, routeDetails)
+	routeDetails := result.value
+	if result.stale {
+		routeDetails.Stale = true
+	}
 
-	return &routeDetails, nil
+	return routeDetails, nil
 }
 
-// GetPredictions fetches real-time predictions for a specific stop on a route
+// GetPredictions fetches real-time predictions for a specific stop on a route.
+// Predictions may be served from the cache if WithPredictionTTL is set; the
+// age of the underlying data is reported on each prediction.
 func (c *Client) GetPredictions(ctx context.Context, routeID, stopID string) ([]Prediction, error) {
 	if routeID == "" {
 		return nil, ErrRouteIDRequired
@@ -264,50 +501,98 @@ func (c *Client) GetPredictions(ctx context.Context, routeID, stopID string) ([]
 		return nil, ErrStopIDRequired
 	}
 
-	url := fmt.Sprintf("%s/v2.0/riders/agencies/sfmta-cis/nstops/%s:%s/predictions", c.baseURL, routeID, stopID)
+	agency := c.agencyFor(ctx)
+	cacheKey := buildCacheKey(CacheKindPredictions, agency, routeID, stopID)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
+	result, err := fetchCached(ctx, c, cacheKey, func(ctx context.Context) ([]PredictionResponse, error) {
+		url := fmt.Sprintf("%s/v2.0/riders/agencies/%s/nstops/%s:%s/predictions", c.baseURL, agency, routeID, stopID)
 
-	req.Header.Set("Accept", "application/json")
+		var predictionResponse []PredictionResponse
+		if err := c.fetchJSON(ctx, EndpointPredictions, url, &predictionResponse); err != nil {
+			return nil, err
+		}
 
-	resp, err := c.httpClient.Do(req)
+		return predictionResponse, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer closeBody(resp.Body)
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	predictions := convertPredictions(result.value, result.fetchedAt, time.Now())
+	for i := range predictions {
+		if result.stale || time.Duration(predictions[i].AgeSeconds)*time.Second > c.predictionStaleAfter {
+			predictions[i].Stale = true
+		}
 	}
 
-	var predictionResponse []PredictionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&predictionResponse); err != nil {
-		return nil, err
-	}
+	return predictions, nil
+}
 
+// convertPredictions converts a prediction response fetched at the given time
+// to simplified predictions as of now. Arrival times are measured from the
+// server's timestamp rather than the local clock, then shifted by how long
+// ago the response was fetched, so clock skew and cached data don't make
+// vehicles look closer or further away than they are.
+func convertPredictions(predictionResponse []PredictionResponse, fetchedAt, now time.Time) []Prediction {
 	// If there are no prediction responses or no values in the first response, return empty predictions
 	if len(predictionResponse) == 0 || len(predictionResponse[0].Values) == 0 {
-		return []Prediction{}, nil
+		return []Prediction{}
+	}
+
+	age := int(now.Sub(fetchedAt).Seconds())
+
+	serverTimestamp := predictionResponse[0].ServerTimestamp
+	predictedAt := fetchedAt
+	if serverTimestamp > 0 {
+		predictedAt = time.UnixMilli(serverTimestamp)
 	}
 
+	route := predictionResponse[0].Route
+	stop := predictionResponse[0].Stop
+
 	// Convert prediction response to predictions
 	predictions := make([]Prediction, len(predictionResponse[0].Values))
 	for i, val := range predictionResponse[0].Values {
+		// Time until arrival when the prediction was made, falling back to the
+		// API's own minutes when the response has no server timestamp
+		untilAtFetch := time.Duration(val.Minutes) * time.Minute
+		if serverTimestamp > 0 && val.Timestamp > 0 {
+			untilAtFetch = time.UnixMilli(val.Timestamp).Sub(predictedAt)
+		}
+		secondsUntil := int(untilAtFetch.Seconds()) - age
+
 		predictions[i] = Prediction{
-			VehicleID:       val.VehicleID,
-			Minutes:         val.Minutes,
-			Direction:       val.Direction.Name,
-			DestinationName: val.Direction.DestinationName,
-			Timestamp:       time.Unix(val.Timestamp/1000, 0),
-			VehicleType:     val.VehicleType,
-			IsDeparture:     val.IsDeparture,
+			RouteID:              route.ID,
+			RouteTitle:           route.Title,
+			RouteColor:           route.Color,
+			StopID:               stop.ID,
+			StopName:             stop.Name,
+			StopCode:             stop.Code,
+			StopLat:              stop.Lat,
+			StopLon:              stop.Lon,
+			VehicleID:            val.VehicleID,
+			Minutes:              max(0, secondsUntil/60),
+			SecondsUntil:         secondsUntil,
+			DirectionID:          val.Direction.ID,
+			Direction:            val.Direction.Name,
+			DestinationName:      val.Direction.DestinationName,
+			Timestamp:            fetchedAt.Add(untilAtFetch),
+			VehicleType:          val.VehicleType,
+			IsDeparture:          val.IsDeparture,
+			TripID:               val.TripID,
+			DelaySeconds:         val.Delay,
+			AffectedByLayover:    val.AffectedByLayover,
+			Occupancy:            parseOccupancy(val.OccupancyStatus, val.OccupancyDescription),
+			OccupancyDescription: val.OccupancyDescription,
+			VehiclesInConsist:    val.VehiclesInConsist,
+			LinkedVehicleIDs:     parseLinkedVehicleIDs(val.LinkedVehicleIds),
+			PredictedAt:          predictedAt,
+			FetchedAt:            fetchedAt,
+			AgeSeconds:           age,
 		}
 	}
 
-	return predictions, nil
+	return predictions
 }
 
 // RouteInfo represents basic information about a MUNI route from the API
@@ -320,6 +605,7 @@ type RouteInfo struct {
 	TextColor   string `json:"textColor"`
 	Hidden      bool   `json:"hidden"`
 	Timestamp   string `json:"timestamp"`
+	Stale       bool   `json:"stale,omitempty"`
 }
 
 // BoundingBox represents the geographical bounds of a route
@@ -377,6 +663,8 @@ type RouteDetails struct {
 	Directions  []Direction `json:"directions"`
 	Paths       []Path      `json:"paths"`
 	Timestamp   string      `json:"timestamp"`
+	Stale       bool        `json:"stale,omitempty"`
+	Alerts      []Alert     `json:"alerts,omitempty"`
 }
 
 // PredictionDirection represents information about the direction of a prediction
@@ -446,13 +734,38 @@ type PredictionResponse struct {
 	Values          []PredictionValue `json:"values"`
 }
 
-// Prediction represents a simplified prediction for a vehicle arrival/departure
+// Prediction represents a prediction for a vehicle arrival/departure. DelaySeconds
+// is how far the vehicle is behind schedule, and is negative when it is early.
+// SecondsUntil counts down from the server's prediction and goes negative once
+// the vehicle is due; Timestamp is the arrival time on the local clock.
+// PredictedAt is the server's timestamp for the prediction.
 type Prediction struct {
-	VehicleID       string    `json:"vehicle_id"`
-	Minutes         int       `json:"minutes"`
-	Direction       string    `json:"direction"`
-	DestinationName string    `json:"destination_name"`
-	Timestamp       time.Time `json:"timestamp"`
-	VehicleType     string    `json:"vehicle_type"`
-	IsDeparture     bool      `json:"is_departure"`
+	RouteID              string    `json:"route_id,omitempty"`
+	RouteTitle           string    `json:"route_title,omitempty"`
+	RouteColor           string    `json:"route_color,omitempty"`
+	StopID               string    `json:"stop_id,omitempty"`
+	StopName             string    `json:"stop_name,omitempty"`
+	StopCode             string    `json:"stop_code,omitempty"`
+	StopLat              float64   `json:"stop_lat,omitempty"`
+	StopLon              float64   `json:"stop_lon,omitempty"`
+	VehicleID            string    `json:"vehicle_id"`
+	Minutes              int       `json:"minutes"`
+	SecondsUntil         int       `json:"seconds_until"`
+	DirectionID          string    `json:"direction_id,omitempty"`
+	Direction            string    `json:"direction"`
+	DestinationName      string    `json:"destination_name"`
+	Timestamp            time.Time `json:"timestamp"`
+	VehicleType          string    `json:"vehicle_type"`
+	IsDeparture          bool      `json:"is_departure"`
+	TripID               string    `json:"trip_id,omitempty"`
+	DelaySeconds         int       `json:"delay_seconds"`
+	AffectedByLayover    bool      `json:"affected_by_layover"`
+	Occupancy            Occupancy `json:"occupancy"`
+	OccupancyDescription string    `json:"occupancy_description,omitempty"`
+	VehiclesInConsist    int       `json:"vehicles_in_consist,omitempty"`
+	LinkedVehicleIDs     []string  `json:"linked_vehicle_ids,omitempty"`
+	PredictedAt          time.Time `json:"predicted_at"`
+	FetchedAt            time.Time `json:"fetched_at"`
+	AgeSeconds           int       `json:"age_seconds"`
+	Stale                bool      `json:"stale,omitempty"`
 }
@@ -2,6 +2,8 @@ package muni
 
 import (
 	"context"
+	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -9,6 +11,7 @@ import (
 func TestNewClient(t *testing.T) {
 	baseURL := "http://test.com"
 	client := NewClient(baseURL)
+	defer client.Close()
 
 	if client.baseURL != baseURL {
 		t.Errorf("Expected baseURL to be %s, got %s", baseURL, client.baseURL)
@@ -27,12 +30,16 @@ func TestClientOptions(t *testing.T) {
 	// Test WithCacheTTL option
 	ttl := 10 * time.Minute
 	client := NewClient("http://test.com", WithCacheTTL(ttl))
+	defer client.Close()
+
 	if client.cache.ttl != ttl {
 		t.Errorf("Expected cache TTL to be %v, got %v", ttl, client.cache.ttl)
 	}
 
 	// Test WithoutCache option
 	client = NewClient("http://test.com", WithoutCache())
+	defer client.Close()
+
 	if client.cache.isEnabled {
 		t.Error("Expected cache to be disabled")
 	}
@@ -43,6 +50,8 @@ func TestGetAllRoutes(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL)
+	defer client.Close()
+
 	routes, err := client.GetAllRoutes(context.Background())
 
 	if err != nil {
@@ -77,6 +86,7 @@ func TestGetRouteDetails(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL)
+	defer client.Close()
 
 	// Test with valid route ID
 	routeID := "N"
@@ -110,6 +120,7 @@ func TestGetPredictions(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(server.URL)
+	defer client.Close()
 
 	// Test with valid route and stop IDs
 	routeID := "N"
@@ -150,8 +161,168 @@ func TestGetPredictions(t *testing.T) {
 	}
 }
 
+func TestGetPredictionsFullPayload(t *testing.T) {
+	server := mockServer(mockJPredictionsResponse)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	defer client.Close()
+
+	predictions, err := client.GetPredictions(context.Background(), "J", "1234")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(predictions) != 2 {
+		t.Fatalf("Expected 2 predictions, got %d", len(predictions))
+	}
+
+	prediction := predictions[0]
+	if prediction.StopID != "1234" || prediction.StopName != "Ocean Beach" {
+		t.Errorf("Expected stop 1234 Ocean Beach, got %s %s", prediction.StopID, prediction.StopName)
+	}
+
+	if prediction.DirectionID != "J_IB" {
+		t.Errorf("Expected direction ID to be J_IB, got %s", prediction.DirectionID)
+	}
+
+	if prediction.TripID != "5678" {
+		t.Errorf("Expected trip ID to be 5678, got %s", prediction.TripID)
+	}
+
+	if prediction.DelaySeconds != 120 {
+		t.Errorf("Expected delay to be 120 seconds, got %d", prediction.DelaySeconds)
+	}
+
+	if prediction.Occupancy != OccupancyFewSeatsAvailable {
+		t.Errorf("Expected occupancy to be %s, got %s", OccupancyFewSeatsAvailable, prediction.Occupancy)
+	}
+
+	if prediction.VehiclesInConsist != 2 {
+		t.Errorf("Expected 2 vehicles in consist, got %d", prediction.VehiclesInConsist)
+	}
+
+	if !reflect.DeepEqual(prediction.LinkedVehicleIDs, []string{"2001", "2002"}) {
+		t.Errorf("Expected linked vehicle IDs [2001 2002], got %v", prediction.LinkedVehicleIDs)
+	}
+
+	// The second vehicle has no occupancy sensor and is held by a layover
+	prediction = predictions[1]
+	if prediction.Occupancy != OccupancyUnknown {
+		t.Errorf("Expected occupancy to be %s, got %s", OccupancyUnknown, prediction.Occupancy)
+	}
+
+	if !prediction.AffectedByLayover {
+		t.Error("Expected prediction to be affected by layover")
+	}
+
+	if prediction.LinkedVehicleIDs != nil {
+		t.Errorf("Expected no linked vehicle IDs, got %v", prediction.LinkedVehicleIDs)
+	}
+}
+
+func TestConvertPredictionsUsesServerTimestamp(t *testing.T) {
+	var response []PredictionResponse
+	if err := json.Unmarshal([]byte(mockPredictionsResponse), &response); err != nil {
+		t.Fatalf("Failed to unmarshal fixture: %v", err)
+	}
+
+	// The local clock is a day ahead of the server; only the server's own
+	// timestamps should matter
+	fetchedAt := time.UnixMilli(1710936000000).Add(24 * time.Hour)
+
+	tests := []struct {
+		age          time.Duration
+		secondsUntil int
+		minutes      int
+	}{
+		{0, 300, 5},
+		{90 * time.Second, 210, 3},
+		{7 * time.Minute, -120, 0},
+	}
+
+	for _, test := range tests {
+		predictions := convertPredictions(response, fetchedAt, fetchedAt.Add(test.age))
+		prediction := predictions[0]
+
+		if prediction.SecondsUntil != test.secondsUntil {
+			t.Errorf("Expected seconds until to be %d after %v, got %d", test.secondsUntil, test.age, prediction.SecondsUntil)
+		}
+
+		if prediction.Minutes != test.minutes {
+			t.Errorf("Expected minutes to be %d after %v, got %d", test.minutes, test.age, prediction.Minutes)
+		}
+
+		if !prediction.Timestamp.Equal(fetchedAt.Add(5 * time.Minute)) {
+			t.Errorf("Expected timestamp to be 5 minutes after fetch, got %v", prediction.Timestamp)
+		}
+
+		if !prediction.PredictedAt.Equal(time.UnixMilli(1710936000000)) {
+			t.Errorf("Expected predicted at to be the server timestamp, got %v", prediction.PredictedAt)
+		}
+	}
+}
+
+func TestGetPredictionsMarksOldDataStale(t *testing.T) {
+	server := mockServer(mockPredictionsResponse)
+	defer server.Close()
+
+	client := NewClient(server.URL, WithPredictionTTL(time.Hour), WithPredictionStaleAfter(time.Minute))
+	defer client.Close()
+
+	predictions, err := client.GetPredictions(context.Background(), "N", "1234")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if predictions[0].Stale {
+		t.Error("Expected fresh predictions not to be stale")
+	}
+
+	// Age the cached response past the stale threshold
+	key := buildCacheKey(CacheKindPredictions, DefaultAgency, "N", "1234")
+	client.cache.mutex.Lock()
+	client.cache.items[key].Value.(*cacheEntry).storedAt = time.Now().Add(-2 * time.Minute)
+	client.cache.mutex.Unlock()
+
+	predictions, err = client.GetPredictions(context.Background(), "N", "1234")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !predictions[0].Stale {
+		t.Error("Expected predictions from old data to be stale")
+	}
+
+	if predictions[0].SecondsUntil > 180 {
+		t.Errorf("Expected seconds until to account for data age, got %d", predictions[0].SecondsUntil)
+	}
+}
+
+func TestParseOccupancy(t *testing.T) {
+	tests := []struct {
+		status      int
+		description string
+		expected    Occupancy
+	}{
+		{0, "", OccupancyUnknown},
+		{0, "Empty", OccupancyEmpty},
+		{1, "Many Seats Available", OccupancyManySeatsAvailable},
+		{3, "Standing Room Only", OccupancyStandingRoomOnly},
+		{5, "Full", OccupancyFull},
+		{42, "Bursting", OccupancyUnknown},
+	}
+
+	for _, test := range tests {
+		if got := parseOccupancy(test.status, test.description); got != test.expected {
+			t.Errorf("Expected parseOccupancy(%d, %q) to be %s, got %s", test.status, test.description, test.expected, got)
+		}
+	}
+}
+
 func TestCacheOperations(t *testing.T) {
 	client := NewClient("https://test-api.example.com")
+	defer client.Close()
 
 	// Test cache enable/disable
 	client.DisableCache()
@@ -16,6 +16,101 @@ func mockServer(response string) *httptest.Server {
 	}))
 }
 
+// mockAPIServer creates a test server that returns the response registered for each request path
+func mockAPIServer(responses map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(response)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}))
+}
+
+// mockSystemResponses returns responses for a small system of two routes, N and J,
+// that both serve stop 1234
+func mockSystemResponses() map[string]string {
+	const prefix = "/v2.0/riders/agencies/sfmta-cis"
+	return map[string]string{
+		prefix + "/routes":                    mockRoutesResponse,
+		prefix + "/routes/N":                  mockRouteDetailsResponse,
+		prefix + "/routes/J":                  mockJRouteDetailsResponse,
+		prefix + "/routes/N/vehicles":         mockNVehiclesResponse,
+		prefix + "/alerts":                    mockAlertsResponse,
+		prefix + "/nstops/N:1234/predictions": mockPredictionsResponse,
+		prefix + "/nstops/J:1234/predictions": mockJPredictionsResponse,
+		prefix + "/nstops/J:5678/predictions": mockJPredictionsResponse,
+	}
+}
+
+var mockNVehiclesResponse = `[
+	{
+		"id": "1440",
+		"lat": 37.7601,
+		"lon": -122.4687,
+		"heading": 90,
+		"speed": 24.5,
+		"directionId": "IB",
+		"tripId": "11223",
+		"vehicleType": "LRV4",
+		"predictable": true,
+		"secsSinceReport": 10,
+		"timestamp": 1710935990000
+	},
+	{
+		"id": "1461",
+		"lat": 37.7762,
+		"lon": -122.3947,
+		"heading": 270,
+		"speed": 0,
+		"directionId": "OB",
+		"tripId": "11230",
+		"vehicleType": "LRV4",
+		"predictable": false,
+		"secsSinceReport": 95,
+		"timestamp": 1710935905000
+	}
+]`
+
+// mockAlertsResponse has an open-ended alert for the N, one for stop 1234 on
+// the J, and an alert for the J that has already ended
+var mockAlertsResponse = `[
+	{
+		"id": "alert-1",
+		"severity": "SEVERE",
+		"cause": "MAINTENANCE",
+		"effect": "REDUCED_SERVICE",
+		"header": "N Judah replaced by buses",
+		"description": "Buses replace N trains between Ocean Beach and Duboce.",
+		"url": "https://www.sfmta.com/alerts/1",
+		"activePeriods": [{"start": 1700000000000, "end": 0}],
+		"affected": [{"routeId": "N"}]
+	},
+	{
+		"id": "alert-2",
+		"severity": "WARNING",
+		"cause": "TECHNICAL_PROBLEM",
+		"effect": "ACCESSIBILITY_ISSUE",
+		"header": "Elevator out of service",
+		"activePeriods": [{"start": 1700000000000, "end": 0}],
+		"affected": [{"routeId": "J", "stopId": "1234"}]
+	},
+	{
+		"id": "alert-3",
+		"severity": "INFO",
+		"effect": "DETOUR",
+		"header": "J Church detour",
+		"activePeriods": [{"start": 1700000000000, "end": 1700000600000}],
+		"affected": [{"routeId": "J"}]
+	}
+]`
+
 var mockRoutesResponse = `[
 	{
 		"id": "N",
@@ -88,7 +183,7 @@ var mockRouteDetailsResponse = `{
 }`
 
 var mockPredictionsResponse = `[{
-	"serverTimestamp": 1710936000,
+	"serverTimestamp": 1710936000000,
 	"nxbs2RedirectUrl": "",
 	"agency": {
 		"rev": 1,
@@ -115,7 +210,7 @@ var mockPredictionsResponse = `[{
 	},
 	"values": [
 		{
-			"timestamp": 1710936000,
+			"timestamp": 1710936300000,
 			"minutes": 5,
 			"affectedByLayover": false,
 			"isDeparture": false,
@@ -137,3 +232,254 @@ var mockPredictionsResponse = `[{
 		}
 	]
 }]`
+
+var mockJRouteDetailsResponse = `{
+	"id": "J",
+	"rev": 1,
+	"title": "J-Church",
+	"description": "J-Church Line",
+	"color": "339900",
+	"textColor": "FFFFFF",
+	"hidden": false,
+	"boundingBox": {
+		"latMin": 37.7337,
+		"latMax": 37.7749,
+		"lonMin": -122.4294,
+		"lonMax": -122.4194
+	},
+	"stops": [
+		{
+			"id": "1234",
+			"lat": 37.7749,
+			"lon": -122.4194,
+			"name": "Ocean Beach",
+			"hidden": false,
+			"showDestinationSelector": true,
+			"directions": ["J_IB"]
+		},
+		{
+			"id": "5678",
+			"lat": 37.7670,
+			"lon": -122.4290,
+			"name": "Church St & Duboce Ave",
+			"code": "15678",
+			"hidden": false,
+			"showDestinationSelector": true,
+			"directions": ["J_IB"]
+		}
+	],
+	"directions": [
+		{
+			"id": "J_IB",
+			"shortName": "Inbound",
+			"name": "Inbound to Embarcadero",
+			"useForUi": true,
+			"stops": ["5678", "1234"]
+		}
+	],
+	"paths": [
+		{
+			"id": "1",
+			"points": [
+				{
+					"lat": 37.7670,
+					"lon": -122.4290
+				},
+				{
+					"lat": 37.7749,
+					"lon": -122.4194
+				}
+			]
+		}
+	],
+	"timestamp": "2024-03-20T12:00:00Z"
+}`
+
+var mockJPredictionsResponse = `[{
+	"serverTimestamp": 1710936000000,
+	"nxbs2RedirectUrl": "",
+	"agency": {
+		"rev": 1,
+		"id": "sfmta-cis",
+		"name": "San Francisco Municipal Transportation Agency",
+		"shortName": "SFMTA"
+	},
+	"route": {
+		"id": "J",
+		"title": "J-Church",
+		"description": "J-Church Line",
+		"color": "339900",
+		"textColor": "FFFFFF",
+		"hidden": false
+	},
+	"stop": {
+		"id": "1234",
+		"lat": 37.7749,
+		"lon": -122.4194,
+		"name": "Ocean Beach",
+		"hidden": false,
+		"showDestinationSelector": true,
+		"route": "J"
+	},
+	"values": [
+		{
+			"timestamp": 1710936120000,
+			"minutes": 2,
+			"affectedByLayover": false,
+			"isDeparture": false,
+			"occupancyStatus": 2,
+			"occupancyDescription": "Few Seats Available",
+			"vehiclesInConsist": 2,
+			"linkedVehicleIds": "2001,2002",
+			"vehicleId": "2001",
+			"vehicleType": "LRV4",
+			"direction": {
+				"id": "J_IB",
+				"name": "Inbound",
+				"destinationName": "Embarcadero"
+			},
+			"tripId": "5678",
+			"delay": 120,
+			"predUsingNavigationTm": false,
+			"departure": false
+		},
+		{
+			"timestamp": 1710936720000,
+			"minutes": 12,
+			"affectedByLayover": true,
+			"isDeparture": false,
+			"occupancyStatus": 0,
+			"occupancyDescription": "",
+			"vehiclesInConsist": 1,
+			"linkedVehicleIds": "",
+			"vehicleId": "2010",
+			"vehicleType": "LRV4",
+			"direction": {
+				"id": "J_IB",
+				"name": "Inbound",
+				"destinationName": "Embarcadero"
+			},
+			"tripId": "5679",
+			"delay": 0,
+			"predUsingNavigationTm": false,
+			"departure": false
+		}
+	]
+}]`
+
+// mockTwoWayResponses returns the system responses with route J running both
+// inbound and outbound through stop 5678
+func mockTwoWayResponses() map[string]string {
+	const prefix = "/v2.0/riders/agencies/sfmta-cis"
+	responses := mockSystemResponses()
+	responses[prefix+"/routes/J"] = mockJTwoWayRouteDetailsResponse
+	responses[prefix+"/nstops/J:5678/predictions"] = mockJTwoWayPredictionsResponse
+	return responses
+}
+
+var mockJTwoWayRouteDetailsResponse = `{
+	"id": "J",
+	"rev": 2,
+	"title": "J-Church",
+	"description": "J-Church Line",
+	"color": "339900",
+	"textColor": "FFFFFF",
+	"hidden": false,
+	"stops": [
+		{
+			"id": "5678",
+			"lat": 37.7670,
+			"lon": -122.4290,
+			"name": "Church St & Duboce Ave",
+			"code": "15678",
+			"hidden": false,
+			"showDestinationSelector": true,
+			"directions": ["J_IB", "J_OB"]
+		}
+	],
+	"directions": [
+		{
+			"id": "J_IB",
+			"shortName": "Inbound",
+			"name": "Inbound to Embarcadero",
+			"useForUi": true,
+			"stops": ["5678"]
+		},
+		{
+			"id": "J_OB",
+			"shortName": "Outbound",
+			"name": "Outbound to Balboa Park",
+			"useForUi": true,
+			"stops": ["5678"]
+		}
+	],
+	"paths": [],
+	"timestamp": "2024-03-20T12:00:00Z"
+}`
+
+var mockJTwoWayPredictionsResponse = `[{
+	"serverTimestamp": 1710936000000,
+	"route": {
+		"id": "J",
+		"title": "J-Church",
+		"color": "339900",
+		"textColor": "FFFFFF"
+	},
+	"stop": {
+		"id": "5678",
+		"lat": 37.7670,
+		"lon": -122.4290,
+		"name": "Church St & Duboce Ave",
+		"code": "15678"
+	},
+	"values": [
+		{
+			"timestamp": 1710936060000,
+			"minutes": 1,
+			"vehicleId": "2020",
+			"vehicleType": "LRV4",
+			"direction": {
+				"id": "J_OB",
+				"name": "Outbound",
+				"destinationName": "Balboa Park"
+			},
+			"tripId": "6001"
+		},
+		{
+			"timestamp": 1710936240000,
+			"minutes": 4,
+			"vehicleId": "2001",
+			"vehicleType": "LRV4",
+			"direction": {
+				"id": "J_IB",
+				"name": "Inbound",
+				"destinationName": "Embarcadero"
+			},
+			"tripId": "5678"
+		},
+		{
+			"timestamp": 1710936660000,
+			"minutes": 11,
+			"vehicleId": "2021",
+			"vehicleType": "LRV4",
+			"direction": {
+				"id": "J_OB",
+				"name": "Outbound",
+				"destinationName": "Balboa Park"
+			},
+			"tripId": "6002"
+		},
+		{
+			"timestamp": 1710937200000,
+			"minutes": 20,
+			"vehicleId": "2010",
+			"vehicleType": "LRV4",
+			"direction": {
+				"id": "J_IB",
+				"name": "Inbound",
+				"destinationName": "Embarcadero"
+			},
+			"tripId": "5679"
+		}
+	]
+}]`
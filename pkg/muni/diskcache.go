@@ -0,0 +1,151 @@
+package muni
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// diskEntryExt is the file extension used for persisted cache entries
+const diskEntryExt = ".json"
+
+// diskEntry is the on-disk representation of a cache entry
+type diskEntry struct {
+	Key        string          `json:"key"`
+	Data       json.RawMessage `json:"data"`
+	StoredAt   time.Time       `json:"stored_at"`
+	Expiration time.Time       `json:"expiration"`
+	StaleUntil time.Time       `json:"stale_until"`
+}
+
+// diskStore persists cache entries as one JSON file per key in a directory
+type diskStore struct {
+	dir string
+}
+
+// newDiskStore creates a disk store in dir, creating the directory if needed
+func newDiskStore(dir string) (*diskStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &diskStore{dir: dir}, nil
+}
+
+// path returns the file used to store the given key
+func (d *diskStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+diskEntryExt)
+}
+
+// save writes an entry to disk, replacing any previous version atomically
+func (d *diskStore) save(entry *cacheEntry) error {
+	data, err := json.Marshal(diskEntry{
+		Key:        entry.key,
+		Data:       entry.data,
+		StoredAt:   entry.storedAt,
+		Expiration: entry.expiration,
+		StaleUntil: entry.staleUntil,
+	})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(d.dir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), d.path(entry.key))
+}
+
+// remove deletes the entry for key from disk
+func (d *diskStore) remove(key string) error {
+	err := os.Remove(d.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// load reads all entries from disk. Unreadable entries and entries past their
+// stale window are deleted and skipped.
+func (d *diskStore) load() ([]*cacheEntry, error) {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*cacheEntry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), diskEntryExt) {
+			continue
+		}
+
+		path := filepath.Join(d.dir, file.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var stored diskEntry
+		if err := json.Unmarshal(data, &stored); err != nil || stored.Key == "" {
+			_ = os.Remove(path)
+			continue
+		}
+
+		entry := &cacheEntry{
+			key:        stored.Key,
+			kind:       cacheKind(stored.Key),
+			data:       stored.Data,
+			storedAt:   stored.StoredAt,
+			expiration: stored.Expiration,
+			staleUntil: stored.StaleUntil,
+		}
+		if entry.isDead() {
+			_ = os.Remove(path)
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// clear deletes all entries from disk
+func (d *diskStore) clear() error {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), diskEntryExt) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(d.dir, file.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package muni
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDiskCacheSurvivesRestart(t *testing.T) {
+	var calls int32
+	server := slowServer(mockRouteDetailsResponse, 0, &calls)
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	client := NewClient(server.URL, WithCacheDir(dir))
+	if _, err := client.GetRouteDetails(context.Background(), "N"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	client.Close()
+
+	// A new client reads the persisted entry instead of calling the API
+	client = NewClient(server.URL, WithCacheDir(dir))
+	defer client.Close()
+
+	details, err := client.GetRouteDetails(context.Background(), "N")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if details.ID != "N" {
+		t.Errorf("Expected route ID to be N, got %s", details.ID)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", got)
+	}
+}
+
+func TestDiskCacheClear(t *testing.T) {
+	dir := t.TempDir()
+
+	client := NewClient("http://test.com", WithCacheDir(dir))
+	defer client.Close()
+
+	client.cache.set("test", "data")
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file in the cache directory, got %d", len(files))
+	}
+
+	client.ClearCache()
+
+	files, err = os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(files) != 0 {
+		t.Errorf("Expected cache directory to be empty after clear, got %d files", len(files))
+	}
+}
+
+func TestDiskCacheClearDuringSet(t *testing.T) {
+	dir := t.TempDir()
+
+	client := NewClient("http://test.com", WithCacheDir(dir))
+	defer client.Close()
+
+	// A large entry takes a while to write to disk
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.cache.set("test", strings.Repeat("x", 8<<20))
+	}()
+
+	// Clear as soon as the entry is in memory, while it may still be being written
+	for {
+		if _, _, state := client.cache.recheck("test"); state != cacheMiss {
+			break
+		}
+		time.Sleep(100 * time.Microsecond)
+	}
+	client.ClearCache()
+	<-done
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(files) != 0 {
+		t.Errorf("Expected cache directory to be empty after clear, got %d files", len(files))
+	}
+
+	// A restart doesn't bring the cleared entry back
+	restarted := NewClient("http://test.com", WithCacheDir(dir))
+	defer restarted.Close()
+
+	if _, _, state := restarted.cache.recheck("test"); state != cacheMiss {
+		t.Error("Expected cleared entry not to be loaded from disk")
+	}
+}
+
+func TestDiskCacheSkipsDeadEntries(t *testing.T) {
+	dir := t.TempDir()
+
+	client := NewClient("http://test.com", WithCacheDir(dir), WithCacheTTL(time.Millisecond), WithStaleWindow(0))
+	client.cache.set("test", "data")
+	client.Close()
+
+	time.Sleep(5 * time.Millisecond)
+
+	client = NewClient("http://test.com", WithCacheDir(dir))
+	defer client.Close()
+
+	var result string
+	if _, state := client.cache.lookup("test", &result); state != cacheMiss {
+		t.Error("Expected expired entry not to be loaded from disk")
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(files) != 0 {
+		t.Errorf("Expected expired entry file to be removed, got %d files", len(files))
+	}
+}
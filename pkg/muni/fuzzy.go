@@ -0,0 +1,149 @@
+package muni
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Scores for how well a query token matches a name token
+const (
+	exactTokenScore  = 1.0
+	prefixTokenScore = 0.8
+	typoTokenScore   = 0.6
+)
+
+// minMatchScore is the lowest score a name must reach to match a query
+const minMatchScore = 0.6
+
+// stopWords are left out when comparing names, so "Church and Duboce" and
+// "Church & Duboce" compare equal
+var stopWords = map[string]bool{
+	"and": true,
+	"at":  true,
+	"of":  true,
+	"the": true,
+}
+
+// abbreviations maps spelled out street words to the abbreviations MUNI uses
+var abbreviations = map[string]string{
+	"street":    "st",
+	"avenue":    "ave",
+	"av":        "ave",
+	"boulevard": "blvd",
+	"drive":     "dr",
+	"road":      "rd",
+	"place":     "pl",
+	"terrace":   "ter",
+	"station":   "sta",
+}
+
+// normalizeTokens lowercases s and splits it into words, dropping punctuation
+// and stop words and abbreviating common street words
+func normalizeTokens(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if stopWords[field] {
+			continue
+		}
+		if abbreviation, ok := abbreviations[field]; ok {
+			field = abbreviation
+		}
+		tokens = append(tokens, field)
+	}
+
+	return tokens
+}
+
+// tokenScore scores how well a query token matches a name token, allowing
+// prefixes and small typos
+func tokenScore(query, name string) float64 {
+	switch {
+	case query == name:
+		return exactTokenScore
+	case len(query) >= 2 && strings.HasPrefix(name, query):
+		return prefixTokenScore
+	}
+
+	// Allow one typo in short words and two in long ones
+	allowed := 0
+	switch {
+	case len(query) >= 8:
+		allowed = 2
+	case len(query) >= 4:
+		allowed = 1
+	}
+
+	if allowed > 0 && editDistance(query, name) <= allowed {
+		return typoTokenScore
+	}
+
+	return 0
+}
+
+// matchScore scores how well a name matches a query, from 0 to 1. Each query
+// token is matched against its best name token; names with fewer unmatched
+// words score slightly higher.
+func matchScore(queryTokens, nameTokens []string) float64 {
+	if len(queryTokens) == 0 || len(nameTokens) == 0 {
+		return 0
+	}
+
+	matched := make([]bool, len(nameTokens))
+	total := 0.0
+	for _, query := range queryTokens {
+		best, bestIndex := 0.0, -1
+		for i, name := range nameTokens {
+			if score := tokenScore(query, name); score > best {
+				best, bestIndex = score, i
+			}
+		}
+		if bestIndex >= 0 {
+			matched[bestIndex] = true
+		}
+		total += best
+	}
+
+	covered := 0
+	for _, m := range matched {
+		if m {
+			covered++
+		}
+	}
+
+	return 0.8*total/float64(len(queryTokens)) + 0.2*float64(covered)/float64(len(nameTokens))
+}
+
+// editDistance returns the number of insertions, deletions, substitutions and
+// transpositions of adjacent letters needed to turn a into b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// Rows for the previous two prefixes of a, needed to detect transpositions
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(rb)]
+}
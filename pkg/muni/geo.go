@@ -0,0 +1,120 @@
+package muni
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Geographic constants
+const (
+	earthRadiusMeters  = 6371000
+	walkingSpeedMPS    = 1.3 // A typical walking pace, about 4.7 km/h
+	stopGridCellDegree = 0.005
+)
+
+// Nearby stop search limits
+const (
+	defaultNearbyRadiusMeters = 500
+	maxNearbyRadiusMeters     = 5000
+	defaultNearbyLimit        = 10
+	maxNearbyLimit            = 50
+)
+
+// ErrInvalidCoordinates is returned when a latitude or longitude is out of range
+var ErrInvalidCoordinates = errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+
+// NearbyStop is a stop near a location, with the distance and an estimated
+// walking time to it
+type NearbyStop struct {
+	StopInfo
+	DistanceMeters int `json:"distance_meters"`
+	WalkingMinutes int `json:"walking_minutes"`
+}
+
+// haversineMeters returns the great-circle distance between two points in meters
+func haversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// walkingMinutes estimates how long it takes to walk a distance, rounded up
+func walkingMinutes(meters float64) int {
+	return int(math.Ceil(meters / walkingSpeedMPS / 60))
+}
+
+// gridCell identifies a cell of the stop grid
+type gridCell struct {
+	lat, lon int
+}
+
+// cellFor returns the grid cell containing a point
+func cellFor(lat, lon float64) gridCell {
+	return gridCell{
+		lat: int(math.Floor(lat / stopGridCellDegree)),
+		lon: int(math.Floor(lon / stopGridCellDegree)),
+	}
+}
+
+// stopGrid buckets stops into cells of a fixed number of degrees so nearby
+// stops can be found without measuring the distance to every stop
+type stopGrid struct {
+	cells map[gridCell][]*StopInfo
+}
+
+// newStopGrid builds a grid from stops
+func newStopGrid(stops []*StopInfo) *stopGrid {
+	grid := &stopGrid{cells: make(map[gridCell][]*StopInfo)}
+	for _, stop := range stops {
+		cell := cellFor(stop.Lat, stop.Lon)
+		grid.cells[cell] = append(grid.cells[cell], stop)
+	}
+	return grid
+}
+
+// nearby returns up to limit stops within radius meters of a point, closest first
+func (g *stopGrid) nearby(lat, lon, radius float64, limit int) []NearbyStop {
+	// Cells are narrower in meters east-west than north-south, away from the equator
+	latCellMeters := stopGridCellDegree * math.Pi / 180 * earthRadiusMeters
+	lonCellMeters := latCellMeters * math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	latCells := int(math.Ceil(radius / latCellMeters))
+	lonCells := int(math.Ceil(radius / lonCellMeters))
+
+	center := cellFor(lat, lon)
+	stops := []NearbyStop{}
+	for dLat := -latCells; dLat <= latCells; dLat++ {
+		for dLon := -lonCells; dLon <= lonCells; dLon++ {
+			for _, stop := range g.cells[gridCell{lat: center.lat + dLat, lon: center.lon + dLon}] {
+				distance := haversineMeters(lat, lon, stop.Lat, stop.Lon)
+				if distance > radius {
+					continue
+				}
+
+				stops = append(stops, NearbyStop{
+					StopInfo:       *stop.clone(),
+					DistanceMeters: int(math.Round(distance)),
+					WalkingMinutes: walkingMinutes(distance),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(stops, func(i, j int) bool {
+		if stops[i].DistanceMeters != stops[j].DistanceMeters {
+			return stops[i].DistanceMeters < stops[j].DistanceMeters
+		}
+		return stops[i].ID < stops[j].ID
+	})
+
+	if len(stops) > limit {
+		stops = stops[:limit]
+	}
+
+	return stops
+}
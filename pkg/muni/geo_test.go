@@ -0,0 +1,99 @@
+package muni
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestHaversineMeters(t *testing.T) {
+	// The two stops in the fixtures are about 1.2 km apart
+	distance := haversineMeters(37.7670, -122.4290, 37.7749, -122.4194)
+	if math.Abs(distance-1218) > 10 {
+		t.Errorf("Expected distance of about 1218m, got %.0fm", distance)
+	}
+
+	if distance := haversineMeters(37.7749, -122.4194, 37.7749, -122.4194); distance != 0 {
+		t.Errorf("Expected distance of 0m between the same point, got %.0fm", distance)
+	}
+}
+
+func TestFindNearbyStops(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	// A point a block from Church & Duboce
+	stops, err := client.FindNearbyStops(context.Background(), 37.7680, -122.4290, 0, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(stops) != 1 || stops[0].ID != "5678" {
+		t.Fatalf("Expected only stop 5678 within 500m, got %+v", stops)
+	}
+
+	if stops[0].DistanceMeters != 111 {
+		t.Errorf("Expected distance of 111m, got %d", stops[0].DistanceMeters)
+	}
+
+	if stops[0].WalkingMinutes != 2 {
+		t.Errorf("Expected walking time of 2 minutes, got %d", stops[0].WalkingMinutes)
+	}
+
+	if len(stops[0].Routes) != 1 || stops[0].Routes[0].RouteID != "J" {
+		t.Errorf("Expected stop 5678 to be served by J, got %+v", stops[0].Routes)
+	}
+
+	// A wider radius finds both stops, closest first
+	stops, err = client.FindNearbyStops(context.Background(), 37.7680, -122.4290, 2000, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(stops) != 2 || stops[0].ID != "5678" || stops[1].ID != "1234" {
+		t.Errorf("Expected stops 5678 and 1234, got %+v", stops)
+	}
+
+	// The limit caps the number of stops
+	stops, err = client.FindNearbyStops(context.Background(), 37.7680, -122.4290, 2000, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(stops) != 1 {
+		t.Errorf("Expected 1 stop, got %d", len(stops))
+	}
+
+	// Test with invalid coordinates
+	if _, err := client.FindNearbyStops(context.Background(), 122.4, 37.7, 0, 0); err != ErrInvalidCoordinates {
+		t.Errorf("Expected ErrInvalidCoordinates, got %v", err)
+	}
+}
+
+func TestFindNearbyStopsResultsDontShareIndex(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	stops, err := client.FindNearbyStops(context.Background(), 37.7680, -122.4290, 0, 0)
+	if err != nil || len(stops) == 0 {
+		t.Fatalf("Expected nearby stops, got %v (%v)", stops, err)
+	}
+
+	// Changing a result must not change the index
+	stops[0].Routes[0].RouteID = "changed"
+
+	stops, err = client.FindNearbyStops(context.Background(), 37.7680, -122.4290, 0, 0)
+	if err != nil || len(stops) == 0 {
+		t.Fatalf("Expected nearby stops, got %v (%v)", stops, err)
+	}
+
+	if stops[0].Routes[0].RouteID == "changed" {
+		t.Error("Expected the index to be unaffected by changes to results")
+	}
+}
@@ -2,17 +2,34 @@ package muni
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 )
 
 // MockClient is a mock implementation of the MUNI client for testing
 type MockClient struct {
-	GetAllRoutesFunc    func(ctx context.Context) ([]RouteInfo, error)
-	GetRouteDetailsFunc func(ctx context.Context, routeID string) (*RouteDetails, error)
-	GetPredictionsFunc  func(ctx context.Context, routeID, stopID string) ([]Prediction, error)
-	ClearCacheFunc      func()
-	EnableCacheFunc     func()
-	DisableCacheFunc    func()
+	GetAllRoutesFunc           func(ctx context.Context) ([]RouteInfo, error)
+	GetRouteDetailsFunc        func(ctx context.Context, routeID string) (*RouteDetails, error)
+	GetPredictionsFunc         func(ctx context.Context, routeID, stopID string) ([]Prediction, error)
+	GetStopPredictionsFunc     func(ctx context.Context, stopID string) ([]Prediction, error)
+	GetFilteredPredictionsFunc func(ctx context.Context, routeID, stopID string, filter PredictionFilter) ([]Prediction, error)
+	GetPredictionsBatchFunc    func(ctx context.Context, requests []PredictionRequest) ([]PredictionResult, error)
+	GetVehicleLocationsFunc    func(ctx context.Context, routeID string) ([]VehicleLocation, error)
+	GetAlertsFunc              func(ctx context.Context, filter AlertFilter) ([]Alert, error)
+	SearchStopsFunc            func(ctx context.Context, query string, limit int) ([]StopMatch, error)
+	FindNearbyStopsFunc        func(ctx context.Context, lat, lon, radiusMeters float64, limit int) ([]NearbyStop, error)
+	ResolveStopCodeFunc        func(ctx context.Context, code string) (*StopInfo, error)
+	ResolveRouteFunc           func(ctx context.Context, name string) (*RouteInfo, error)
+	GetStopDetailsFunc         func(ctx context.Context, stopID string) (*StopInfo, error)
+	GetRouteStopSequenceFunc   func(ctx context.Context, routeID, directionID string) (*RouteStopSequence, error)
+	GetStopsBetweenFunc        func(ctx context.Context, routeID, fromStopID, toStopID string) (*StopsBetween, error)
+	FindDirectRoutesFunc       func(ctx context.Context, fromStopID, toStopID string) ([]DirectRoute, error)
+	ClearCacheFunc             func()
+	InvalidateCacheFunc        func(filter CacheFilter) int
+	EnableCacheFunc            func()
+	DisableCacheFunc           func()
+	CacheStatsFunc             func() CacheStats
 }
 
 // Ensure MockClient implements required interface
@@ -20,14 +37,29 @@ var _ interface {
 	GetAllRoutes(ctx context.Context) ([]RouteInfo, error)
 	GetRouteDetails(ctx context.Context, routeID string) (*RouteDetails, error)
 	GetPredictions(ctx context.Context, routeID, stopID string) ([]Prediction, error)
+	GetStopPredictions(ctx context.Context, stopID string) ([]Prediction, error)
+	GetFilteredPredictions(ctx context.Context, routeID, stopID string, filter PredictionFilter) ([]Prediction, error)
+	GetPredictionsBatch(ctx context.Context, requests []PredictionRequest) ([]PredictionResult, error)
+	GetVehicleLocations(ctx context.Context, routeID string) ([]VehicleLocation, error)
+	GetAlerts(ctx context.Context, filter AlertFilter) ([]Alert, error)
+	SearchStops(ctx context.Context, query string, limit int) ([]StopMatch, error)
+	FindNearbyStops(ctx context.Context, lat, lon, radiusMeters float64, limit int) ([]NearbyStop, error)
+	ResolveStopCode(ctx context.Context, code string) (*StopInfo, error)
+	ResolveRoute(ctx context.Context, name string) (*RouteInfo, error)
+	GetStopDetails(ctx context.Context, stopID string) (*StopInfo, error)
+	GetRouteStopSequence(ctx context.Context, routeID, directionID string) (*RouteStopSequence, error)
+	GetStopsBetween(ctx context.Context, routeID, fromStopID, toStopID string) (*StopsBetween, error)
+	FindDirectRoutes(ctx context.Context, fromStopID, toStopID string) ([]DirectRoute, error)
 	ClearCache()
+	InvalidateCache(filter CacheFilter) int
 	EnableCache()
 	DisableCache()
+	CacheStats() CacheStats
 } = (*MockClient)(nil)
 
 // NewMockClient creates a new mock MUNI client with default implementations
 func NewMockClient() *MockClient {
-	return &MockClient{
+	m := &MockClient{
 		GetAllRoutesFunc: func(ctx context.Context) ([]RouteInfo, error) {
 			return []RouteInfo{
 				{
@@ -134,16 +166,305 @@ func NewMockClient() *MockClient {
 				},
 			}, nil
 		},
+		GetStopPredictionsFunc: func(ctx context.Context, stopID string) ([]Prediction, error) {
+			if stopID == "" {
+				return nil, ErrStopIDRequired
+			}
+
+			return []Prediction{
+				{
+					RouteID:         "J",
+					RouteTitle:      "J Church",
+					RouteColor:      "a96614",
+					VehicleID:       "1501",
+					Minutes:         3,
+					Direction:       "Inbound",
+					DestinationName: "Embarcadero",
+					Timestamp:       time.Now().Add(3 * time.Minute),
+					VehicleType:     "LRV4",
+				},
+				{
+					RouteID:         "N",
+					RouteTitle:      "N Judah",
+					RouteColor:      "005b95",
+					VehicleID:       "2046",
+					Minutes:         7,
+					Direction:       "Inbound",
+					DestinationName: "Caltrain",
+					Timestamp:       time.Now().Add(7 * time.Minute),
+					VehicleType:     "LRV4",
+				},
+			}, nil
+		},
+		GetVehicleLocationsFunc: func(ctx context.Context, routeID string) ([]VehicleLocation, error) {
+			if routeID == "" {
+				return nil, ErrRouteIDRequired
+			}
+
+			return []VehicleLocation{
+				{
+					ID:                 "1440",
+					RouteID:            routeID,
+					Lat:                37.7601,
+					Lon:                -122.4687,
+					Heading:            90,
+					SpeedKmh:           24.5,
+					DirectionID:        "IB",
+					VehicleType:        "LRV4",
+					Predictable:        true,
+					LastReportAt:       time.Now().Add(-10 * time.Second),
+					SecondsSinceReport: 10,
+					FetchedAt:          time.Now(),
+				},
+			}, nil
+		},
+		GetAlertsFunc: func(ctx context.Context, filter AlertFilter) ([]Alert, error) {
+			return []Alert{}, nil
+		},
+		SearchStopsFunc: func(ctx context.Context, query string, limit int) ([]StopMatch, error) {
+			if query == "" {
+				return nil, ErrQueryRequired
+			}
+
+			return []StopMatch{
+				{
+					StopInfo: StopInfo{
+						ID:   "3860",
+						Code: "13860",
+						Name: "Church St & Duboce Ave",
+						Lat:  37.7693,
+						Lon:  -122.4290,
+						Routes: []StopRoute{
+							{
+								RouteID:    "J",
+								RouteTitle: "J Church",
+								Directions: []StopDirection{{ID: "DIR_1", Name: "Inbound to Downtown"}},
+							},
+						},
+					},
+					Score: 0.9,
+				},
+			}, nil
+		},
+		FindNearbyStopsFunc: func(ctx context.Context, lat, lon, radiusMeters float64, limit int) ([]NearbyStop, error) {
+			if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+				return nil, ErrInvalidCoordinates
+			}
+
+			return []NearbyStop{
+				{
+					StopInfo: StopInfo{
+						ID:     "3860",
+						Code:   "13860",
+						Name:   "Church St & Duboce Ave",
+						Lat:    37.7693,
+						Lon:    -122.4290,
+						Routes: []StopRoute{{RouteID: "J", RouteTitle: "J Church"}},
+					},
+					DistanceMeters: 120,
+					WalkingMinutes: 2,
+				},
+			}, nil
+		},
+		ResolveStopCodeFunc: func(ctx context.Context, code string) (*StopInfo, error) {
+			if code == "" {
+				return nil, ErrStopCodeRequired
+			}
+
+			if code != "13860" {
+				return nil, fmt.Errorf("%w: %s", ErrStopCodeNotFound, code)
+			}
+
+			return &StopInfo{
+				ID:     "3860",
+				Code:   "13860",
+				Name:   "Church St & Duboce Ave",
+				Lat:    37.7693,
+				Lon:    -122.4290,
+				Routes: []StopRoute{{RouteID: "J", RouteTitle: "J Church"}},
+			}, nil
+		},
+		ResolveRouteFunc: func(ctx context.Context, name string) (*RouteInfo, error) {
+			if name == "" {
+				return nil, ErrRouteIDRequired
+			}
+
+			// Treat every name as a route ID
+			return &RouteInfo{ID: name}, nil
+		},
+		GetStopDetailsFunc: func(ctx context.Context, stopID string) (*StopInfo, error) {
+			if stopID == "" {
+				return nil, ErrStopIDRequired
+			}
+
+			return &StopInfo{
+				ID:   stopID,
+				Code: "1" + stopID,
+				Name: "Church St & Duboce Ave",
+				Lat:  37.7693,
+				Lon:  -122.4290,
+				Routes: []StopRoute{
+					{
+						RouteID:        "J",
+						RouteTitle:     "J Church",
+						RouteColor:     "a96614",
+						RouteTextColor: "ffffff",
+						Directions:     []StopDirection{{ID: "DIR_1", Name: "Inbound to Downtown"}},
+					},
+					{
+						RouteID:        "N",
+						RouteTitle:     "N Judah",
+						RouteColor:     "005b95",
+						RouteTextColor: "ffffff",
+						Directions:     []StopDirection{{ID: "DIR_1", Name: "Inbound to Caltrain"}},
+					},
+				},
+			}, nil
+		},
+		GetStopsBetweenFunc: func(ctx context.Context, routeID, fromStopID, toStopID string) (*StopsBetween, error) {
+			if routeID == "" {
+				return nil, ErrRouteIDRequired
+			}
+
+			if fromStopID == "" || toStopID == "" {
+				return nil, ErrStopIDRequired
+			}
+
+			if fromStopID == toStopID {
+				return nil, ErrSameStop
+			}
+
+			return &StopsBetween{
+				RouteID:       routeID,
+				RouteTitle:    routeID + " Test Route",
+				DirectionID:   "DIR_1",
+				DirectionName: "Inbound to Downtown",
+				From:          RouteStop{Sequence: 3, ID: fromStopID, Name: "Test Stop 1", DistanceMeters: 820},
+				To:            RouteStop{Sequence: 5, ID: toStopID, Name: "Test Stop 3", DistanceMeters: 1640},
+				Stops: []RouteStop{
+					{Sequence: 4, ID: "3861", Name: "Test Stop 2", DistanceMeters: 1230},
+				},
+				StopCount:      2,
+				DistanceMeters: 820,
+			}, nil
+		},
+		FindDirectRoutesFunc: func(ctx context.Context, fromStopID, toStopID string) ([]DirectRoute, error) {
+			if fromStopID == "" || toStopID == "" {
+				return nil, ErrStopIDRequired
+			}
+
+			if fromStopID == toStopID {
+				return nil, ErrSameStop
+			}
+
+			return []DirectRoute{
+				{
+					StopsBetween: StopsBetween{
+						RouteID:       "J",
+						RouteTitle:    "J Church",
+						DirectionID:   "DIR_1",
+						DirectionName: "Inbound to Downtown",
+						From:          RouteStop{Sequence: 3, ID: fromStopID, Name: "Test Stop 1", DistanceMeters: 820},
+						To:            RouteStop{Sequence: 5, ID: toStopID, Name: "Test Stop 3", DistanceMeters: 1640},
+						Stops: []RouteStop{
+							{Sequence: 4, ID: "3861", Name: "Test Stop 2", DistanceMeters: 1230},
+						},
+						StopCount:      2,
+						DistanceMeters: 820,
+					},
+					RouteColor: "a96614",
+					Predictions: []Prediction{
+						{
+							RouteID:         "J",
+							VehicleID:       "1501",
+							Minutes:         3,
+							SecondsUntil:    180,
+							DirectionID:     "DIR_1",
+							Direction:       "Inbound",
+							DestinationName: "Downtown",
+							Timestamp:       time.Now().Add(3 * time.Minute),
+							VehicleType:     "LRV4",
+						},
+					},
+				},
+			}, nil
+		},
 		ClearCacheFunc: func() {
 			// Do nothing in the mock
 		},
+		InvalidateCacheFunc: func(filter CacheFilter) int {
+			return 0
+		},
 		EnableCacheFunc: func() {
 			// Do nothing in the mock
 		},
 		DisableCacheFunc: func() {
 			// Do nothing in the mock
 		},
+		CacheStatsFunc: func() CacheStats {
+			return CacheStats{
+				Enabled: true,
+				Kinds:   map[string]CacheKindStats{},
+				Keys:    []CacheKeyStats{},
+			}
+		},
+	}
+
+	// Filtered predictions filter the single prediction mock by direction and destination by default
+	m.GetFilteredPredictionsFunc = func(ctx context.Context, routeID, stopID string, filter PredictionFilter) ([]Prediction, error) {
+		if filter.MaxMinutes < 0 || filter.Limit < 0 {
+			return nil, ErrInvalidPredictionFilter
+		}
+
+		predictions, err := m.GetPredictionsFunc(ctx, routeID, stopID)
+		if err != nil {
+			return nil, err
+		}
+
+		direction := strings.ToLower(filter.Direction)
+		return filterPredictions(predictions, func(p Prediction) bool {
+			return strings.EqualFold(p.DirectionID, filter.Direction) ||
+				strings.Contains(strings.ToLower(p.Direction), direction) ||
+				strings.Contains(strings.ToLower(p.DestinationName), direction)
+		}, filter), nil
+	}
+
+	// Stop sequences are built from the route details mock by default
+	m.GetRouteStopSequenceFunc = func(ctx context.Context, routeID, directionID string) (*RouteStopSequence, error) {
+		if directionID == "" {
+			return nil, ErrDirectionRequired
+		}
+
+		details, err := m.GetRouteDetailsFunc(ctx, routeID)
+		if err != nil {
+			return nil, err
+		}
+
+		return stopSequenceFor(details, directionID)
+	}
+
+	// Batch predictions use the single prediction mock for each pair by default
+	m.GetPredictionsBatchFunc = func(ctx context.Context, requests []PredictionRequest) ([]PredictionResult, error) {
+		if len(requests) == 0 {
+			return nil, ErrEmptyBatch
+		}
+
+		results := make([]PredictionResult, len(requests))
+		for i, request := range requests {
+			results[i] = PredictionResult{RouteID: request.RouteID, StopID: request.StopID}
+
+			predictions, err := m.GetPredictionsFunc(ctx, request.RouteID, request.StopID)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			results[i].Predictions = predictions
+		}
+
+		return results, nil
 	}
+
+	return m
 }
 
 // GetAllRoutes calls the mock implementation
@@ -161,11 +482,81 @@ func (m *MockClient) GetPredictions(ctx context.Context, routeID, stopID string)
 	return m.GetPredictionsFunc(ctx, routeID, stopID)
 }
 
+// GetStopPredictions calls the mock implementation
+func (m *MockClient) GetStopPredictions(ctx context.Context, stopID string) ([]Prediction, error) {
+	return m.GetStopPredictionsFunc(ctx, stopID)
+}
+
+// GetFilteredPredictions calls the mock implementation
+func (m *MockClient) GetFilteredPredictions(ctx context.Context, routeID, stopID string, filter PredictionFilter) ([]Prediction, error) {
+	return m.GetFilteredPredictionsFunc(ctx, routeID, stopID, filter)
+}
+
+// GetPredictionsBatch calls the mock implementation
+func (m *MockClient) GetPredictionsBatch(ctx context.Context, requests []PredictionRequest) ([]PredictionResult, error) {
+	return m.GetPredictionsBatchFunc(ctx, requests)
+}
+
+// GetVehicleLocations calls the mock implementation
+func (m *MockClient) GetVehicleLocations(ctx context.Context, routeID string) ([]VehicleLocation, error) {
+	return m.GetVehicleLocationsFunc(ctx, routeID)
+}
+
+// GetAlerts calls the mock implementation
+func (m *MockClient) GetAlerts(ctx context.Context, filter AlertFilter) ([]Alert, error) {
+	return m.GetAlertsFunc(ctx, filter)
+}
+
+// SearchStops calls the mock implementation
+func (m *MockClient) SearchStops(ctx context.Context, query string, limit int) ([]StopMatch, error) {
+	return m.SearchStopsFunc(ctx, query, limit)
+}
+
+// FindNearbyStops calls the mock implementation
+func (m *MockClient) FindNearbyStops(ctx context.Context, lat, lon, radiusMeters float64, limit int) ([]NearbyStop, error) {
+	return m.FindNearbyStopsFunc(ctx, lat, lon, radiusMeters, limit)
+}
+
+// ResolveStopCode calls the mock implementation
+func (m *MockClient) ResolveStopCode(ctx context.Context, code string) (*StopInfo, error) {
+	return m.ResolveStopCodeFunc(ctx, code)
+}
+
+// ResolveRoute calls the mock implementation
+func (m *MockClient) ResolveRoute(ctx context.Context, name string) (*RouteInfo, error) {
+	return m.ResolveRouteFunc(ctx, name)
+}
+
+// GetStopDetails calls the mock implementation
+func (m *MockClient) GetStopDetails(ctx context.Context, stopID string) (*StopInfo, error) {
+	return m.GetStopDetailsFunc(ctx, stopID)
+}
+
+// GetRouteStopSequence calls the mock implementation
+func (m *MockClient) GetRouteStopSequence(ctx context.Context, routeID, directionID string) (*RouteStopSequence, error) {
+	return m.GetRouteStopSequenceFunc(ctx, routeID, directionID)
+}
+
+// GetStopsBetween calls the mock implementation
+func (m *MockClient) GetStopsBetween(ctx context.Context, routeID, fromStopID, toStopID string) (*StopsBetween, error) {
+	return m.GetStopsBetweenFunc(ctx, routeID, fromStopID, toStopID)
+}
+
+// FindDirectRoutes calls the mock implementation
+func (m *MockClient) FindDirectRoutes(ctx context.Context, fromStopID, toStopID string) ([]DirectRoute, error) {
+	return m.FindDirectRoutesFunc(ctx, fromStopID, toStopID)
+}
+
 // ClearCache calls the mock implementation
 func (m *MockClient) ClearCache() {
 	m.ClearCacheFunc()
 }
 
+// InvalidateCache calls the mock implementation
+func (m *MockClient) InvalidateCache(filter CacheFilter) int {
+	return m.InvalidateCacheFunc(filter)
+}
+
 // EnableCache calls the mock implementation
 func (m *MockClient) EnableCache() {
 	m.EnableCacheFunc()
@@ -175,3 +566,8 @@ func (m *MockClient) EnableCache() {
 func (m *MockClient) DisableCache() {
 	m.DisableCacheFunc()
 }
+
+// CacheStats calls the mock implementation
+func (m *MockClient) CacheStats() CacheStats {
+	return m.CacheStatsFunc()
+}
@@ -0,0 +1,57 @@
+package muni
+
+import "strings"
+
+// Occupancy describes how crowded a vehicle is, following the GTFS-realtime
+// occupancy status levels
+type Occupancy string
+
+// Occupancy levels reported for predicted vehicles
+const (
+	OccupancyUnknown                 Occupancy = "unknown"
+	OccupancyEmpty                   Occupancy = "empty"
+	OccupancyManySeatsAvailable      Occupancy = "many_seats_available"
+	OccupancyFewSeatsAvailable       Occupancy = "few_seats_available"
+	OccupancyStandingRoomOnly        Occupancy = "standing_room_only"
+	OccupancyCrushedStandingRoomOnly Occupancy = "crushed_standing_room_only"
+	OccupancyFull                    Occupancy = "full"
+	OccupancyNotAcceptingPassengers  Occupancy = "not_accepting_passengers"
+)
+
+// occupancyLevels maps the API's numeric occupancy status to an Occupancy
+var occupancyLevels = map[int]Occupancy{
+	0: OccupancyEmpty,
+	1: OccupancyManySeatsAvailable,
+	2: OccupancyFewSeatsAvailable,
+	3: OccupancyStandingRoomOnly,
+	4: OccupancyCrushedStandingRoomOnly,
+	5: OccupancyFull,
+	6: OccupancyNotAcceptingPassengers,
+}
+
+// parseOccupancy converts the API's occupancy status to an Occupancy. The API
+// reports status 0 with no description when a vehicle has no occupancy
+// sensor, so a missing description means the occupancy is unknown.
+func parseOccupancy(status int, description string) Occupancy {
+	if description == "" {
+		return OccupancyUnknown
+	}
+
+	if occupancy, ok := occupancyLevels[status]; ok {
+		return occupancy
+	}
+
+	return OccupancyUnknown
+}
+
+// parseLinkedVehicleIDs splits the API's comma-separated list of vehicles
+// coupled into a single consist
+func parseLinkedVehicleIDs(ids string) []string {
+	var result []string
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package muni
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+// directRoutePredictionLimit is how many upcoming departures are attached to
+// each direct route
+const directRoutePredictionLimit = 3
+
+// DirectRoute is a one-seat ride between two stops on one direction of a
+// route, with the next departures from the first stop in that direction.
+// PredictionsError is set instead of failing the whole search when
+// predictions for the route couldn't be fetched.
+type DirectRoute struct {
+	StopsBetween
+	RouteColor       string       `json:"route_color,omitempty"`
+	Predictions      []Prediction `json:"predictions"`
+	PredictionsError string       `json:"predictions_error,omitempty"`
+}
+
+// FindDirectRoutes finds every route and direction that runs from one stop to
+// another without a transfer, using the stop index to narrow down the routes
+// and their direction stop sequences to check the order. Each ride comes with
+// live predictions at the first stop and rides are sorted by the next
+// departure, then by the number of stops. No direct routes is not an error.
+func (c *Client) FindDirectRoutes(ctx context.Context, fromStopID, toStopID string) ([]DirectRoute, error) {
+	if fromStopID == "" || toStopID == "" {
+		return nil, ErrStopIDRequired
+	}
+
+	if fromStopID == toStopID {
+		return nil, ErrSameStop
+	}
+
+	from, err := c.GetStopDetails(ctx, fromStopID)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := c.GetStopDetails(ctx, toStopID)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := []DirectRoute{}
+	var requests []PredictionRequest
+	for _, route := range from.Routes {
+		if !slices.ContainsFunc(to.Routes, func(r StopRoute) bool { return r.RouteID == route.RouteID }) {
+			continue
+		}
+
+		details, err := c.GetRouteDetails(ctx, route.RouteID)
+		if err != nil {
+			return nil, err
+		}
+
+		rides := ridesBetween(details, fromStopID, toStopID)
+		if len(rides) == 0 {
+			continue
+		}
+
+		for _, ride := range rides {
+			routes = append(routes, DirectRoute{StopsBetween: ride, RouteColor: route.RouteColor})
+		}
+		requests = append(requests, PredictionRequest{RouteID: route.RouteID, StopID: fromStopID})
+	}
+
+	if len(requests) > 0 {
+		results, err := c.GetPredictionsBatch(ctx, requests)
+		if err != nil {
+			return nil, err
+		}
+
+		attachDirectRoutePredictions(routes, results)
+	}
+
+	slices.SortStableFunc(routes, compareDirectRoutes)
+
+	return routes, nil
+}
+
+// attachDirectRoutePredictions gives each direct route the soonest
+// predictions for its route heading in its direction
+func attachDirectRoutePredictions(routes []DirectRoute, results []PredictionResult) {
+	for i := range routes {
+		route := &routes[i]
+		route.Predictions = []Prediction{}
+
+		for _, result := range results {
+			if result.RouteID != route.RouteID {
+				continue
+			}
+
+			if result.Error != "" {
+				route.PredictionsError = result.Error
+				break
+			}
+
+			route.Predictions = filterPredictions(result.Predictions, func(p Prediction) bool {
+				return p.DirectionID == "" || p.DirectionID == route.DirectionID
+			}, PredictionFilter{Limit: directRoutePredictionLimit})
+			break
+		}
+	}
+}
+
+// compareDirectRoutes orders direct routes by their next departure, with
+// routes that have no predictions last, then by the number of stops
+func compareDirectRoutes(a, b DirectRoute) int {
+	switch {
+	case len(a.Predictions) > 0 && len(b.Predictions) > 0:
+		if c := cmp.Compare(a.Predictions[0].SecondsUntil, b.Predictions[0].SecondsUntil); c != 0 {
+			return c
+		}
+	case len(a.Predictions) > 0:
+		return -1
+	case len(b.Predictions) > 0:
+		return 1
+	}
+
+	return cmp.Compare(a.StopCount, b.StopCount)
+}
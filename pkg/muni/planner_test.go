@@ -0,0 +1,115 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestFindDirectRoutes(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	routes, err := client.FindDirectRoutes(context.Background(), "5678", "1234")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(routes) != 1 {
+		t.Fatalf("Expected 1 direct route, got %d", len(routes))
+	}
+
+	route := routes[0]
+	if route.RouteID != "J" || route.DirectionID != "J_IB" || route.RouteColor != "339900" {
+		t.Errorf("Expected J inbound in color 339900, got %s %s in %s", route.RouteID, route.DirectionID, route.RouteColor)
+	}
+
+	if route.StopCount != 1 || route.DistanceMeters != 1218 {
+		t.Errorf("Expected 1 stop over 1218 meters, got %d over %d", route.StopCount, route.DistanceMeters)
+	}
+
+	if len(route.Predictions) != 2 || route.Predictions[0].VehicleID != "2001" {
+		t.Errorf("Expected 2 predictions starting with vehicle 2001, got %+v", route.Predictions)
+	}
+
+	// J only runs inbound, so there's no direct route back
+	routes, err = client.FindDirectRoutes(context.Background(), "1234", "5678")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(routes) != 0 {
+		t.Errorf("Expected no direct routes, got %d", len(routes))
+	}
+}
+
+func TestFindDirectRoutesPredictionError(t *testing.T) {
+	responses := mockSystemResponses()
+	delete(responses, "/v2.0/riders/agencies/sfmta-cis/nstops/J:5678/predictions")
+
+	server := mockAPIServer(responses)
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	routes, err := client.FindDirectRoutes(context.Background(), "5678", "1234")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(routes) != 1 {
+		t.Fatalf("Expected 1 direct route, got %d", len(routes))
+	}
+
+	if routes[0].PredictionsError == "" || len(routes[0].Predictions) != 0 {
+		t.Errorf("Expected a predictions error and no predictions, got %+v", routes[0])
+	}
+}
+
+func TestFindDirectRoutesErrors(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	if _, err := client.FindDirectRoutes(context.Background(), "", "1234"); err != ErrStopIDRequired {
+		t.Errorf("Expected ErrStopIDRequired, got %v", err)
+	}
+
+	if _, err := client.FindDirectRoutes(context.Background(), "1234", "1234"); err != ErrSameStop {
+		t.Errorf("Expected ErrSameStop, got %v", err)
+	}
+
+	if _, err := client.FindDirectRoutes(context.Background(), "9999", "1234"); !errors.Is(err, ErrStopNotFound) {
+		t.Errorf("Expected ErrStopNotFound, got %v", err)
+	}
+}
+
+func TestCompareDirectRoutes(t *testing.T) {
+	departingIn := func(seconds int) []Prediction {
+		return []Prediction{{SecondsUntil: seconds}}
+	}
+
+	routes := []DirectRoute{
+		{StopsBetween: StopsBetween{RouteID: "no predictions, few stops", StopCount: 2}},
+		{StopsBetween: StopsBetween{RouteID: "later", StopCount: 3}, Predictions: departingIn(600)},
+		{StopsBetween: StopsBetween{RouteID: "sooner, more stops", StopCount: 9}, Predictions: departingIn(120)},
+		{StopsBetween: StopsBetween{RouteID: "sooner, fewer stops", StopCount: 4}, Predictions: departingIn(120)},
+		{StopsBetween: StopsBetween{RouteID: "no predictions, many stops", StopCount: 12}},
+	}
+
+	slices.SortStableFunc(routes, compareDirectRoutes)
+
+	expected := []string{"sooner, fewer stops", "sooner, more stops", "later", "no predictions, few stops", "no predictions, many stops"}
+	for i, route := range routes {
+		if route.RouteID != expected[i] {
+			t.Errorf("Expected %q at index %d, got %q", expected[i], i, route.RouteID)
+		}
+	}
+}
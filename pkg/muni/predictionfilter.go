@@ -0,0 +1,140 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Prediction filter errors
+var (
+	ErrDirectionNotFound       = errors.New("direction not found")
+	ErrInvalidPredictionFilter = errors.New("max minutes and limit must not be negative")
+)
+
+// PredictionFilter narrows down the predictions for a route and stop.
+// Direction is a direction ID, a short name such as "Inbound", or part of a
+// direction's name or destination such as "Embarcadero". MaxMinutes drops
+// predictions arriving further away than that, and Limit caps how many are
+// returned. Zero values don't filter.
+type PredictionFilter struct {
+	Direction  string
+	MaxMinutes int
+	Limit      int
+}
+
+// GetFilteredPredictions gets predictions for a specific stop on a route,
+// keeping only those heading in the filter's direction and arriving within
+// its time window. The direction is resolved against the route's directions,
+// falling back to the destinations on the predictions themselves.
+func (c *Client) GetFilteredPredictions(ctx context.Context, routeID, stopID string, filter PredictionFilter) ([]Prediction, error) {
+	if filter.MaxMinutes < 0 || filter.Limit < 0 {
+		return nil, ErrInvalidPredictionFilter
+	}
+
+	predictions, err := c.GetPredictions(ctx, routeID, stopID)
+	if err != nil {
+		return nil, err
+	}
+
+	match := func(Prediction) bool { return true }
+	if filter.Direction != "" {
+		details, err := c.GetRouteDetails(ctx, routeID)
+		if err != nil {
+			return nil, err
+		}
+
+		match, err = directionMatcher(details, filter.Direction, predictions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return filterPredictions(predictions, match, filter), nil
+}
+
+// directionMatcher returns a function reporting whether a prediction is
+// heading in the queried direction of the route
+func directionMatcher(details *RouteDetails, query string, predictions []Prediction) (func(Prediction) bool, error) {
+	directionIDs := make(map[string]bool)
+	for _, direction := range resolveDirections(details.Directions, query) {
+		directionIDs[direction.ID] = true
+	}
+
+	if len(directionIDs) > 0 {
+		return func(p Prediction) bool { return directionIDs[p.DirectionID] }, nil
+	}
+
+	// Fall back to the destinations shown on the predictions
+	destination := strings.ToLower(strings.TrimSpace(query))
+	match := func(p Prediction) bool {
+		return strings.Contains(strings.ToLower(p.DestinationName), destination) ||
+			strings.Contains(strings.ToLower(p.Direction), destination)
+	}
+	if slices.ContainsFunc(predictions, match) {
+		return match, nil
+	}
+
+	return nil, directionNotFoundError(details, query)
+}
+
+// directionNotFoundError reports an unknown direction along with the
+// directions the route does run
+func directionNotFoundError(details *RouteDetails, query string) error {
+	names := make([]string, len(details.Directions))
+	for i, direction := range details.Directions {
+		names[i] = direction.Name
+		if direction.ShortName != "" && direction.ShortName != direction.Name {
+			names[i] = fmt.Sprintf("%s (%s)", direction.ShortName, direction.Name)
+		}
+	}
+
+	return fmt.Errorf("%w: %q; route %s runs %s", ErrDirectionNotFound, query, details.ID, strings.Join(names, ", "))
+}
+
+// resolveDirections returns the directions whose ID, short name or name equal
+// the query, ignoring case, or failing that, whose name contains it
+func resolveDirections(directions []Direction, query string) []Direction {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	var exact, partial []Direction
+	for _, direction := range directions {
+		switch {
+		case strings.EqualFold(direction.ID, query),
+			strings.EqualFold(direction.ShortName, query),
+			strings.EqualFold(direction.Name, query):
+			exact = append(exact, direction)
+		case strings.Contains(strings.ToLower(direction.Name), query):
+			partial = append(partial, direction)
+		}
+	}
+
+	if len(exact) > 0 {
+		return exact
+	}
+
+	return partial
+}
+
+// filterPredictions keeps the predictions that match and arrive within the
+// filter's time window, up to the filter's limit
+func filterPredictions(predictions []Prediction, match func(Prediction) bool, filter PredictionFilter) []Prediction {
+	filtered := []Prediction{}
+	for _, prediction := range predictions {
+		if filter.Limit > 0 && len(filtered) == filter.Limit {
+			break
+		}
+
+		if filter.MaxMinutes > 0 && prediction.Minutes > filter.MaxMinutes {
+			continue
+		}
+
+		if match(prediction) {
+			filtered = append(filtered, prediction)
+		}
+	}
+
+	return filtered
+}
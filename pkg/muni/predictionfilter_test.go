@@ -0,0 +1,71 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetFilteredPredictions(t *testing.T) {
+	server := mockAPIServer(mockTwoWayResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	tests := []struct {
+		name     string
+		filter   PredictionFilter
+		vehicles []string
+	}{
+		{"no filter", PredictionFilter{}, []string{"2020", "2001", "2021", "2010"}},
+		{"direction ID", PredictionFilter{Direction: "J_IB"}, []string{"2001", "2010"}},
+		{"short name", PredictionFilter{Direction: "outbound"}, []string{"2020", "2021"}},
+		{"direction name", PredictionFilter{Direction: "embarcadero"}, []string{"2001", "2010"}},
+		{"destination", PredictionFilter{Direction: "Balboa"}, []string{"2020", "2021"}},
+		{"max minutes", PredictionFilter{Direction: "Inbound", MaxMinutes: 10}, []string{"2001"}},
+		{"limit", PredictionFilter{Limit: 3}, []string{"2020", "2001", "2021"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			predictions, err := client.GetFilteredPredictions(context.Background(), "J", "5678", tt.filter)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(predictions) != len(tt.vehicles) {
+				t.Fatalf("Expected %d predictions, got %d", len(tt.vehicles), len(predictions))
+			}
+
+			for i, prediction := range predictions {
+				if prediction.VehicleID != tt.vehicles[i] {
+					t.Errorf("Expected vehicle %s at index %d, got %s", tt.vehicles[i], i, prediction.VehicleID)
+				}
+			}
+		})
+	}
+}
+
+func TestGetFilteredPredictionsErrors(t *testing.T) {
+	server := mockAPIServer(mockTwoWayResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	_, err := client.GetFilteredPredictions(context.Background(), "J", "5678", PredictionFilter{Direction: "northbound"})
+	if !errors.Is(err, ErrDirectionNotFound) {
+		t.Fatalf("Expected ErrDirectionNotFound, got %v", err)
+	}
+
+	expected := `direction not found: "northbound"; route J runs Inbound (Inbound to Embarcadero), Outbound (Outbound to Balboa Park)`
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err.Error())
+	}
+
+	_, err = client.GetFilteredPredictions(context.Background(), "J", "5678", PredictionFilter{Limit: -1})
+	if !errors.Is(err, ErrInvalidPredictionFilter) {
+		t.Errorf("Expected ErrInvalidPredictionFilter, got %v", err)
+	}
+}
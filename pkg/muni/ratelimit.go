@@ -0,0 +1,137 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when a request exceeds a rate limit configured to reject
+var ErrRateLimited = errors.New("client rate limit exceeded")
+
+// RateLimit describes a token bucket budget for API requests
+type RateLimit struct {
+	// RequestsPerSecond is the sustained request rate. Zero or less disables the limit.
+	RequestsPerSecond float64
+	// Burst is the number of requests that may be made at once. Defaults to
+	// RequestsPerSecond rounded up, with a minimum of 1.
+	Burst int
+	// Reject makes requests beyond the budget fail immediately with ErrRateLimited
+	// instead of queueing until a token is available
+	Reject bool
+}
+
+// tokenBucket is a token bucket limiter. Waiting callers reserve a token up
+// front, so queued requests are served in arrival order.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	reject bool
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+// newTokenBucket creates a full token bucket for the given limit, or nil if the limit is disabled
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	if limit.RequestsPerSecond <= 0 {
+		return nil
+	}
+
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = math.Max(1, math.Ceil(limit.RequestsPerSecond))
+	}
+
+	return &tokenBucket{
+		rate:   limit.RequestsPerSecond,
+		burst:  burst,
+		reject: limit.Reject,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last update. Caller must hold the mutex.
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// wait takes a token, blocking until one is available or the context is done.
+// Buckets configured to reject return ErrRateLimited instead of blocking.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	b.refill(time.Now())
+
+	if b.reject && b.tokens < 1 {
+		b.mutex.Unlock()
+		return ErrRateLimited
+	}
+
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mutex.Unlock()
+
+	if err := sleepContext(ctx, delay); err != nil {
+		// Give the reserved token back so cancelled callers don't slow down others
+		b.release()
+		return err
+	}
+
+	return nil
+}
+
+// release gives back a token taken by a request that was never sent
+func (b *tokenBucket) release() {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	b.refill(time.Now())
+	b.tokens = math.Min(b.burst, b.tokens+1)
+	b.mutex.Unlock()
+}
+
+// rateLimiter combines a client-wide budget with optional per-endpoint budgets
+type rateLimiter struct {
+	global    *tokenBucket
+	endpoints map[string]*tokenBucket
+}
+
+// newRateLimiter creates a rate limiter with no limits configured
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		endpoints: make(map[string]*tokenBucket),
+	}
+}
+
+// wait blocks until a request to the given endpoint fits within both the
+// endpoint's budget and the client-wide budget
+func (l *rateLimiter) wait(ctx context.Context, endpoint string) error {
+	bucket := l.endpoints[endpoint]
+	if err := bucket.wait(ctx); err != nil {
+		return err
+	}
+
+	if err := l.global.wait(ctx); err != nil {
+		// The request won't be sent, so it mustn't use up the endpoint's budget
+		bucket.release()
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimitRejectsBeyondBudget(t *testing.T) {
+	server := mockServer(mockRoutesResponse)
+	defer server.Close()
+
+	client := NewClient(server.URL, WithoutCache(), WithRateLimit(RateLimit{
+		RequestsPerSecond: 0.01,
+		Burst:             1,
+		Reject:            true,
+	}))
+	defer client.Close()
+
+	if _, err := client.GetAllRoutes(context.Background()); err != nil {
+		t.Fatalf("Unexpected error on first request: %v", err)
+	}
+
+	_, err := client.GetAllRoutes(context.Background())
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+}
+
+func TestRateLimitQueuesBeyondBudget(t *testing.T) {
+	server := mockServer(mockRoutesResponse)
+	defer server.Close()
+
+	client := NewClient(server.URL, WithoutCache(), WithRateLimit(RateLimit{
+		RequestsPerSecond: 50,
+		Burst:             1,
+	}))
+	defer client.Close()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetAllRoutes(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Two of the three requests must wait ~20ms each for a token
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("Expected requests to be spaced out by the rate limit, took %v", elapsed)
+	}
+}
+
+func TestRateLimitWaitHonorsContext(t *testing.T) {
+	bucket := newTokenBucket(RateLimit{RequestsPerSecond: 0.01, Burst: 1})
+
+	if err := bucket.wait(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := bucket.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context deadline error, got %v", err)
+	}
+}
+
+func TestEndpointRateLimitIsIndependent(t *testing.T) {
+	server := mockServer(mockPredictionsResponse)
+	defer server.Close()
+
+	client := NewClient(server.URL, WithoutCache(), WithEndpointRateLimit(EndpointPredictions, RateLimit{
+		RequestsPerSecond: 0.01,
+		Burst:             1,
+		Reject:            true,
+	}))
+	defer client.Close()
+
+	if _, err := client.GetPredictions(context.Background(), "N", "1234"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := client.GetPredictions(context.Background(), "N", "1234"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited for predictions, got %v", err)
+	}
+
+	// Route metadata has its own budget and is unaffected
+	if _, err := client.GetAllRoutes(context.Background()); err != nil {
+		t.Errorf("Expected routes request to succeed, got %v", err)
+	}
+}
+
+func TestEndpointTokenReturnedWhenGlobalLimitRejects(t *testing.T) {
+	limiter := newRateLimiter()
+	limiter.global = newTokenBucket(RateLimit{RequestsPerSecond: 0.01, Burst: 1, Reject: true})
+	limiter.endpoints[EndpointPredictions] = newTokenBucket(RateLimit{RequestsPerSecond: 0.01, Burst: 2, Reject: true})
+
+	// Use up the client-wide budget on another endpoint
+	if err := limiter.wait(context.Background(), EndpointRoutes); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := limiter.wait(context.Background(), EndpointPredictions); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Expected ErrRateLimited, got %v", err)
+		}
+	}
+
+	// Rejected requests must not have used up the prediction budget
+	if tokens := limiter.endpoints[EndpointPredictions].tokens; tokens < 2 {
+		t.Errorf("Expected the prediction budget to stay at 2 tokens, got %v", tokens)
+	}
+}
@@ -0,0 +1,154 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how failed API requests are retried.
+// Requests are retried on network errors, 429 Too Many Requests and 5xx responses.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 1 are treated as 1 (no retries).
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. A Retry-After asking for a
+	// longer wait stops the retries, returning the error instead.
+	MaxBackoff time.Duration
+	// Multiplier is applied to the backoff after each failed attempt
+	Multiplier float64
+	// Jitter is the fraction (0-1) of each backoff that is randomized
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns the retry policy used by NewClient
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+	}
+}
+
+// NoRetry returns a retry policy that makes a single attempt
+func NoRetry() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// do runs fn until it succeeds, returns a permanent error, the attempts are
+// exhausted or the context is cancelled. The last error is returned.
+func (p RetryPolicy) do(ctx context.Context, fn func() error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if waitErr := sleepContext(ctx, p.delay(attempt, err)); waitErr != nil {
+				return errors.Join(err, waitErr)
+			}
+		}
+
+		err = fn()
+		if err == nil || !isRetryable(ctx, err) || p.retryAfterTooLong(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// delay computes the jittered backoff before the given retry attempt
+func (p RetryPolicy) delay(attempt int, lastErr error) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	for i := 1; i < attempt; i++ {
+		backoff *= multiplier
+	}
+
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	jitter := p.Jitter
+	if jitter < 0 {
+		jitter = 0
+	} else if jitter > 1 {
+		jitter = 1
+	}
+	d := time.Duration(backoff * (1 - jitter*rand.Float64()))
+
+	// Honor the server's Retry-After hint when it asks us to wait longer
+	var apiErr *APIError
+	if errors.As(lastErr, &apiErr) && apiErr.RetryAfter > d {
+		d = apiErr.RetryAfter
+	}
+
+	return d
+}
+
+// retryAfterTooLong reports whether the server asked to wait longer than the
+// policy's MaxBackoff before retrying. Retrying sooner would only be rejected
+// again and count against the rate limit.
+func (p RetryPolicy) retryAfterTooLong(err error) bool {
+	var apiErr *APIError
+	return p.MaxBackoff > 0 && errors.As(err, &apiErr) && apiErr.RetryAfter > p.MaxBackoff
+}
+
+// isRetryable reports whether err is a transient failure worth retrying
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || isContextError(err) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+
+	// *url.Error is itself a net.Error, so look at what it wraps. Permanent
+	// failures such as an unsupported scheme or a malformed URL aren't retried.
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// sleepContext waits for the given duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
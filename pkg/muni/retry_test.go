@@ -0,0 +1,256 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fastRetryPolicy retries quickly so tests don't sleep
+var fastRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+	Multiplier:     2,
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "upstream unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(mockRoutesResponse))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(fastRetryPolicy))
+	defer client.Close()
+
+	routes, err := client.GetAllRoutes(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(routes) != 2 {
+		t.Errorf("Expected 2 routes, got %d", len(routes))
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "no such route", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(fastRetryPolicy))
+	defer client.Close()
+
+	_, err := client.GetRouteDetails(context.Background(), "XX")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %v", err)
+	}
+
+	if apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code 404, got %d", apiErr.StatusCode)
+	}
+
+	if apiErr.Endpoint != EndpointRouteDetails {
+		t.Errorf("Expected endpoint %s, got %s", EndpointRouteDetails, apiErr.Endpoint)
+	}
+
+	if apiErr.Body != "no such route" {
+		t.Errorf("Expected body snippet 'no such route', got %q", apiErr.Body)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	_, err := client.GetPredictions(context.Background(), "N", "1234")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %v", err)
+	}
+
+	if apiErr.RetryAfter != 7*time.Second {
+		t.Errorf("Expected RetryAfter to be 7s, got %v", apiErr.RetryAfter)
+	}
+
+	if !apiErr.Temporary() {
+		t.Error("Expected 429 to be temporary")
+	}
+}
+
+func TestRetryWaitsForRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(mockRoutesResponse))
+	}))
+	defer server.Close()
+
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Second}
+	client := NewClient(server.URL, WithRetryPolicy(policy))
+	defer client.Close()
+
+	start := time.Now()
+	if _, err := client.GetAllRoutes(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected the retry to wait the full Retry-After, took %v", elapsed)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
+
+func TestNoRetryWhenRetryAfterExceedsMaxBackoff(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(fastRetryPolicy))
+	defer client.Close()
+
+	start := time.Now()
+	_, err := client.GetAllRoutes(context.Background())
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected *APIError, got %v", err)
+	}
+
+	if apiErr.RetryAfter != time.Minute {
+		t.Errorf("Expected RetryAfter to be 1m, got %v", apiErr.RetryAfter)
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the error to be returned without waiting, took %v", elapsed)
+	}
+}
+
+func TestRetryHonorsContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour}
+	client := NewClient(server.URL, WithRetryPolicy(policy))
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetAllRoutes(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context deadline error, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected retry to stop on context cancellation, took %v", elapsed)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	_, parseErr := url.Parse("http://bad host/")
+
+	req, err := http.NewRequest("GET", "ftp://example.com/routes", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, schemeErr := http.DefaultClient.Do(req)
+
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"malformed URL", parseErr, false},
+		{"unsupported scheme", schemeErr, false},
+		{"client error", &APIError{StatusCode: http.StatusNotFound}, false},
+		{"server error", &APIError{StatusCode: http.StatusBadGateway}, true},
+		{"connection reset", &url.Error{Op: "Get", URL: "http://example.com", Err: syscall.ECONNRESET}, true},
+		{"connection refused", &url.Error{Op: "Get", URL: "http://example.com",
+			Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, true},
+		{"unexpected EOF", &url.Error{Op: "Get", URL: "http://example.com", Err: io.ErrUnexpectedEOF}, true},
+		{"timeout", &url.Error{Op: "Get", URL: "http://example.com",
+			Err: &net.OpError{Op: "read", Net: "tcp", Err: &net.DNSError{IsTimeout: true}}}, true},
+	}
+
+	for _, test := range tests {
+		if test.err == nil {
+			t.Fatalf("Expected an error for %s", test.name)
+		}
+
+		if got := isRetryable(context.Background(), test.err); got != test.retryable {
+			t.Errorf("Expected %s (%v) retryable to be %v, got %v", test.name, test.err, test.retryable, got)
+		}
+	}
+}
+
+func TestNoRetryOnUnsupportedScheme(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour}
+	client := NewClient("ftp://example.com", WithRetryPolicy(policy))
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := client.GetAllRoutes(ctx)
+	if err == nil {
+		t.Fatal("Expected an error for an unsupported scheme")
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the request to fail without retrying, got %v", err)
+	}
+}
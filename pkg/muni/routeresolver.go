@@ -0,0 +1,176 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Route resolution tuning
+const (
+	// minSuggestionScore is the lowest score for a route to be suggested
+	minSuggestionScore = 0.4
+	// routeScoreMargin is how far the best route must score above the next
+	// for a fuzzy match to be unambiguous
+	routeScoreMargin    = 0.05
+	maxRouteSuggestions = 3
+)
+
+// ErrRouteNotFound is returned when a route name can't be resolved to a route
+var ErrRouteNotFound = errors.New("unknown route")
+
+// routeNoiseWords are words riders add around route names, as in "the N line"
+var routeNoiseWords = map[string]bool{
+	"bus":   true,
+	"line":  true,
+	"muni":  true,
+	"route": true,
+	"train": true,
+}
+
+// routeSuffixes maps spelled out service variants to their route ID suffixes,
+// as in "38 Rapid" for 38R
+var routeSuffixes = map[string]string{
+	"rapid":   "r",
+	"express": "x",
+}
+
+// RouteSuggestion is a route that might be what a rider meant
+type RouteSuggestion struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// RouteNotFoundError is returned when a route name doesn't match any route,
+// or matches several equally well. It lists the closest routes.
+type RouteNotFoundError struct {
+	Query       string
+	Suggestions []RouteSuggestion
+}
+
+// Error implements the error interface
+func (e *RouteNotFoundError) Error() string {
+	msg := fmt.Sprintf("%v: %q", ErrRouteNotFound, e.Query)
+	if len(e.Suggestions) == 0 {
+		return msg
+	}
+
+	names := make([]string, len(e.Suggestions))
+	for i, suggestion := range e.Suggestions {
+		names[i] = fmt.Sprintf("%s (%s)", suggestion.ID, suggestion.Title)
+	}
+
+	return fmt.Sprintf("%s; did you mean %s?", msg, strings.Join(names, ", "))
+}
+
+// Unwrap lets errors.Is match ErrRouteNotFound
+func (e *RouteNotFoundError) Unwrap() error {
+	return ErrRouteNotFound
+}
+
+// routeTokens normalizes a route name into words, dropping noise words
+func routeTokens(s string) []string {
+	var tokens []string
+	for _, token := range normalizeTokens(s) {
+		if !routeNoiseWords[token] {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// compactRouteName joins route name tokens the way route IDs are written, so
+// "38 rapid" and "38-R" both become "38r"
+func compactRouteName(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		if suffix, ok := routeSuffixes[token]; ok {
+			token = suffix
+		}
+		b.WriteString(token)
+	}
+	return b.String()
+}
+
+// routeScore scores how well a rider's route name matches a route, from 0 to 1
+func routeScore(queryTokens []string, route RouteInfo) float64 {
+	query := compactRouteName(queryTokens)
+	id := compactRouteName(normalizeTokens(route.ID))
+	titleTokens := normalizeTokens(route.Title)
+
+	// "38 rapid" for 38R, or "n judah" for "N-Judah"
+	if query == id || query == compactRouteName(titleTokens) {
+		return 1
+	}
+
+	score := matchScore(queryTokens, titleTokens)
+
+	// Near misses on the ID, like "39R" for 38R, are worth suggesting
+	if len(query) <= len(id)+1 && editDistance(query, id) == 1 {
+		score = max(score, minSuggestionScore)
+	}
+
+	return score
+}
+
+// ResolveRoute finds the route a rider means by name, accepting the route ID
+// in any case ("n"), its title ("N-Judah", "n judah"), part of its title
+// ("judah") or a spelled out variant ("the 38 Rapid" for 38R). When no route
+// matches, or several match equally well, it returns a *RouteNotFoundError
+// with suggestions.
+func (c *Client) ResolveRoute(ctx context.Context, name string) (*RouteInfo, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrRouteIDRequired
+	}
+
+	routes, err := c.GetAllRoutes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Exact IDs win outright
+	for i := range routes {
+		if strings.EqualFold(routes[i].ID, name) {
+			return &routes[i], nil
+		}
+	}
+
+	queryTokens := routeTokens(name)
+	if len(queryTokens) == 0 {
+		return nil, &RouteNotFoundError{Query: name}
+	}
+
+	type scoredRoute struct {
+		route *RouteInfo
+		score float64
+	}
+
+	var scored []scoredRoute
+	for i := range routes {
+		if score := routeScore(queryTokens, routes[i]); score >= minSuggestionScore {
+			scored = append(scored, scoredRoute{route: &routes[i], score: score})
+		}
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	if len(scored) > 0 && scored[0].score >= minMatchScore &&
+		(len(scored) == 1 || scored[0].score-scored[1].score >= routeScoreMargin) {
+		return scored[0].route, nil
+	}
+
+	notFound := &RouteNotFoundError{Query: name}
+	for i := 0; i < len(scored) && i < maxRouteSuggestions; i++ {
+		notFound.Suggestions = append(notFound.Suggestions, RouteSuggestion{
+			ID:    scored[i].route.ID,
+			Title: scored[i].route.Title,
+		})
+	}
+
+	return nil, notFound
+}
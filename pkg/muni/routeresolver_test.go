@@ -0,0 +1,102 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+var mockNamedRoutesResponse = `[
+	{"id": "N", "title": "N-Judah"},
+	{"id": "J", "title": "J-Church"},
+	{"id": "38", "title": "38-Geary"},
+	{"id": "38R", "title": "38R-Geary Rapid"}
+]`
+
+func TestResolveRoute(t *testing.T) {
+	server := mockServer(mockNamedRoutesResponse)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	defer client.Close()
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"N", "N"},
+		{"n", "N"},
+		{"N-Judah", "N"},
+		{"n judah", "N"},
+		{"the N line", "N"},
+		{"judah", "N"},
+		{"church", "J"},
+		{"38", "38"},
+		{"38r", "38R"},
+		{"38-R", "38R"},
+		{"the 38 Rapid", "38R"},
+		{"Geary Rapid", "38R"},
+	}
+
+	for _, test := range tests {
+		route, err := client.ResolveRoute(context.Background(), test.name)
+		if err != nil {
+			t.Errorf("Unexpected error resolving %q: %v", test.name, err)
+			continue
+		}
+
+		if route.ID != test.expected {
+			t.Errorf("Expected %q to resolve to %s, got %s", test.name, test.expected, route.ID)
+		}
+	}
+}
+
+func TestResolveRouteSuggestions(t *testing.T) {
+	server := mockServer(mockNamedRoutesResponse)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	defer client.Close()
+
+	// Ambiguous names suggest every close match
+	_, err := client.ResolveRoute(context.Background(), "geary")
+
+	var notFound *RouteNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Expected *RouteNotFoundError, got %v", err)
+	}
+
+	if !errors.Is(err, ErrRouteNotFound) {
+		t.Error("Expected error to match ErrRouteNotFound")
+	}
+
+	if len(notFound.Suggestions) != 2 || notFound.Suggestions[0].ID != "38" || notFound.Suggestions[1].ID != "38R" {
+		t.Errorf("Expected suggestions 38 and 38R, got %+v", notFound.Suggestions)
+	}
+
+	expected := `unknown route: "geary"; did you mean 38 (38-Geary), 38R (38R-Geary Rapid)?`
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err.Error())
+	}
+
+	// Typos in the ID are suggested but not resolved
+	_, err = client.ResolveRoute(context.Background(), "39R")
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Expected *RouteNotFoundError, got %v", err)
+	}
+
+	if len(notFound.Suggestions) == 0 || notFound.Suggestions[0].ID != "38R" {
+		t.Errorf("Expected 38R to be suggested, got %+v", notFound.Suggestions)
+	}
+
+	// Unrelated names have no suggestions
+	_, err = client.ResolveRoute(context.Background(), "cable car to the moon")
+	if !errors.As(err, &notFound) || len(notFound.Suggestions) != 0 {
+		t.Errorf("Expected no suggestions, got %v", err)
+	}
+
+	// Test with an empty name
+	if _, err := client.ResolveRoute(context.Background(), " "); err != ErrRouteIDRequired {
+		t.Errorf("Expected ErrRouteIDRequired, got %v", err)
+	}
+}
@@ -0,0 +1,357 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// maxPathSnapMeters is how far a stop may be from a route's path and still be
+// measured along it
+const maxPathSnapMeters = 150
+
+// Route stop errors
+var (
+	ErrDirectionRequired       = errors.New("direction is required")
+	ErrSameStop                = errors.New("the two stops must be different")
+	ErrStopNotOnRoute          = errors.New("route doesn't serve stop")
+	ErrStopsNotInSameDirection = errors.New("stops are not on the same direction of the route")
+)
+
+// RouteStop is a stop in order along one direction of a route. IsFirst and
+// IsLast mark the terminals, and DistanceMeters is how far along the route's
+// path the stop is from the first stop. DistanceEstimated is set when part of
+// that distance is a straight line because the stops couldn't be placed on
+// the route's path.
+type RouteStop struct {
+	Sequence          int     `json:"sequence"`
+	ID                string  `json:"id"`
+	Code              string  `json:"code,omitempty"`
+	Name              string  `json:"name"`
+	Lat               float64 `json:"lat"`
+	Lon               float64 `json:"lon"`
+	IsFirst           bool    `json:"is_first"`
+	IsLast            bool    `json:"is_last"`
+	DistanceMeters    int     `json:"distance_meters"`
+	DistanceEstimated bool    `json:"distance_estimated,omitempty"`
+
+	// segmentEstimated is set when the distance from the previous stop is estimated
+	segmentEstimated bool
+}
+
+// RouteStopSequence is the ordered list of stops made in one direction of a route
+type RouteStopSequence struct {
+	RouteID       string      `json:"route_id"`
+	RouteTitle    string      `json:"route_title"`
+	DirectionID   string      `json:"direction_id"`
+	DirectionName string      `json:"direction_name"`
+	Stops         []RouteStop `json:"stops"`
+}
+
+// StopsBetween is a ride between two stops along one direction of a route.
+// Stops are the stops passed on the way, StopCount counts every stop after
+// boarding up to and including the destination, and DistanceMeters is
+// measured along the route's path, unless DistanceEstimated is set.
+type StopsBetween struct {
+	RouteID           string      `json:"route_id"`
+	RouteTitle        string      `json:"route_title"`
+	DirectionID       string      `json:"direction_id"`
+	DirectionName     string      `json:"direction_name"`
+	From              RouteStop   `json:"from"`
+	To                RouteStop   `json:"to"`
+	Stops             []RouteStop `json:"stops"`
+	StopCount         int         `json:"stop_count"`
+	DistanceMeters    int         `json:"distance_meters"`
+	DistanceEstimated bool        `json:"distance_estimated,omitempty"`
+}
+
+// GetRouteStopSequence gets the stops a route makes in one direction, in
+// order. The direction may be given by ID, short name or name.
+func (c *Client) GetRouteStopSequence(ctx context.Context, routeID, directionID string) (*RouteStopSequence, error) {
+	if routeID == "" {
+		return nil, ErrRouteIDRequired
+	}
+
+	if directionID == "" {
+		return nil, ErrDirectionRequired
+	}
+
+	details, err := c.GetRouteDetails(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return stopSequenceFor(details, directionID)
+}
+
+// GetStopsBetween gets the stops a route passes between two stops, working
+// out which direction of the route runs from the first stop to the second.
+func (c *Client) GetStopsBetween(ctx context.Context, routeID, fromStopID, toStopID string) (*StopsBetween, error) {
+	if routeID == "" {
+		return nil, ErrRouteIDRequired
+	}
+
+	if fromStopID == "" || toStopID == "" {
+		return nil, ErrStopIDRequired
+	}
+
+	if fromStopID == toStopID {
+		return nil, ErrSameStop
+	}
+
+	details, err := c.GetRouteDetails(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return stopsBetween(details, fromStopID, toStopID)
+}
+
+// stopsBetween finds the first direction of the route, preferring those shown
+// to riders, that reaches the second stop after the first
+func stopsBetween(details *RouteDetails, fromStopID, toStopID string) (*StopsBetween, error) {
+	rides := ridesBetween(details, fromStopID, toStopID)
+	if len(rides) == 0 {
+		return nil, stopsNotConnectedError(details, fromStopID, toStopID)
+	}
+
+	return &rides[0], nil
+}
+
+// ridesBetween returns a ride for every direction of the route that reaches
+// the second stop after the first, directions shown to riders first
+func ridesBetween(details *RouteDetails, fromStopID, toStopID string) []StopsBetween {
+	directions := slices.Clone(details.Directions)
+	slices.SortStableFunc(directions, func(a, b Direction) int {
+		switch {
+		case a.UseForUI == b.UseForUI:
+			return 0
+		case a.UseForUI:
+			return -1
+		default:
+			return 1
+		}
+	})
+
+	var rides []StopsBetween
+	for _, direction := range directions {
+		sequence := buildStopSequence(details, direction)
+
+		from := slices.IndexFunc(sequence.Stops, func(stop RouteStop) bool { return stop.ID == fromStopID })
+		if from < 0 {
+			continue
+		}
+
+		to := slices.IndexFunc(sequence.Stops[from+1:], func(stop RouteStop) bool { return stop.ID == toStopID })
+		if to < 0 {
+			continue
+		}
+		to += from + 1
+
+		rides = append(rides, StopsBetween{
+			RouteID:        sequence.RouteID,
+			RouteTitle:     sequence.RouteTitle,
+			DirectionID:    sequence.DirectionID,
+			DirectionName:  sequence.DirectionName,
+			From:           sequence.Stops[from],
+			To:             sequence.Stops[to],
+			Stops:          slices.Clone(sequence.Stops[from+1 : to]),
+			StopCount:      to - from,
+			DistanceMeters: sequence.Stops[to].DistanceMeters - sequence.Stops[from].DistanceMeters,
+			DistanceEstimated: slices.ContainsFunc(sequence.Stops[from+1:to+1], func(stop RouteStop) bool {
+				return stop.segmentEstimated
+			}),
+		})
+	}
+
+	return rides
+}
+
+// stopsNotConnectedError explains why no direction of the route runs between
+// two stops, naming the directions each stop is on
+func stopsNotConnectedError(details *RouteDetails, fromStopID, toStopID string) error {
+	directionsServing := func(stopID string) []string {
+		var names []string
+		for _, direction := range details.Directions {
+			if slices.Contains(direction.Stops, stopID) {
+				names = append(names, direction.Name)
+			}
+		}
+		return names
+	}
+
+	fromDirections := directionsServing(fromStopID)
+	if len(fromDirections) == 0 {
+		return fmt.Errorf("%w: route %s, stop %s", ErrStopNotOnRoute, details.ID, fromStopID)
+	}
+
+	toDirections := directionsServing(toStopID)
+	if len(toDirections) == 0 {
+		return fmt.Errorf("%w: route %s, stop %s", ErrStopNotOnRoute, details.ID, toStopID)
+	}
+
+	return fmt.Errorf("%w: route %s doesn't run from stop %s (%s) to stop %s (%s)", ErrStopsNotInSameDirection,
+		details.ID, fromStopID, strings.Join(fromDirections, ", "), toStopID, strings.Join(toDirections, ", "))
+}
+
+// stopSequenceFor resolves a direction of the route and builds its stop sequence
+func stopSequenceFor(details *RouteDetails, directionID string) (*RouteStopSequence, error) {
+	directions := resolveDirections(details.Directions, directionID)
+	if len(directions) == 0 {
+		return nil, directionNotFoundError(details, directionID)
+	}
+
+	return buildStopSequence(details, directions[0]), nil
+}
+
+// buildStopSequence resolves a direction's stop IDs against the route's stops
+// and measures the distance traveled to each one. Stop IDs the route doesn't
+// list are skipped.
+func buildStopSequence(details *RouteDetails, direction Direction) *RouteStopSequence {
+	stopsByID := make(map[string]Stop, len(details.Stops))
+	for _, stop := range details.Stops {
+		stopsByID[stop.ID] = stop
+	}
+
+	sequence := &RouteStopSequence{
+		RouteID:       details.ID,
+		RouteTitle:    details.Title,
+		DirectionID:   direction.ID,
+		DirectionName: direction.Name,
+		Stops:         []RouteStop{},
+	}
+
+	var previous Stop
+	var previousSnaps []pathSnap
+	distance := 0.0
+	estimated := false
+	for _, stopID := range direction.Stops {
+		stop, ok := stopsByID[stopID]
+		if !ok {
+			continue
+		}
+
+		snaps := snapToPaths(details.Paths, stop.Lat, stop.Lon)
+		segmentEstimated := false
+		if len(sequence.Stops) > 0 {
+			var meters float64
+			meters, segmentEstimated = segmentMeters(details.Paths, previous, stop, previousSnaps, snaps)
+			distance += meters
+			estimated = estimated || segmentEstimated
+		}
+
+		sequence.Stops = append(sequence.Stops, RouteStop{
+			Sequence:          len(sequence.Stops) + 1,
+			ID:                stop.ID,
+			Code:              stop.Code,
+			Name:              stop.Name,
+			Lat:               stop.Lat,
+			Lon:               stop.Lon,
+			DistanceMeters:    int(math.Round(distance)),
+			DistanceEstimated: estimated,
+			segmentEstimated:  segmentEstimated,
+		})
+
+		previous, previousSnaps = stop, snaps
+	}
+
+	if n := len(sequence.Stops); n > 0 {
+		sequence.Stops[0].IsFirst = true
+		sequence.Stops[n-1].IsLast = true
+	}
+
+	return sequence
+}
+
+// pathSnap is the point on one of a route's paths closest to a stop, and how
+// far away it is
+type pathSnap struct {
+	point  int
+	meters float64
+}
+
+// onPath reports whether the stop is close enough to the path to be measured along it
+func (s pathSnap) onPath() bool {
+	return s.meters <= maxPathSnapMeters
+}
+
+// snapToPaths finds the point closest to a location on each of the route's paths
+func snapToPaths(paths []Path, lat, lon float64) []pathSnap {
+	snaps := make([]pathSnap, len(paths))
+	for i, path := range paths {
+		snaps[i] = pathSnap{point: -1, meters: math.Inf(1)}
+		for j, point := range path.Points {
+			if meters := haversineMeters(lat, lon, point.Lat, point.Lon); meters < snaps[i].meters {
+				snaps[i] = pathSnap{point: j, meters: meters}
+			}
+		}
+	}
+
+	return snaps
+}
+
+// alongPath returns the distance between two points of a path, following it
+func alongPath(points []PathPoint, from, to int) float64 {
+	start, end := min(from, to), max(from, to)
+
+	meters := 0.0
+	for i := start; i < end; i++ {
+		meters += haversineMeters(points[i].Lat, points[i].Lon, points[i+1].Lat, points[i+1].Lon)
+	}
+
+	return meters
+}
+
+// segmentMeters returns the distance traveled between two consecutive stops.
+// Each path both stops lie on is measured, since the route's other direction
+// or a branch may run past them too, and the shortest is used. Stops on
+// different paths are measured across the ends where the paths meet, for
+// route lines split into several paths. If the stops can't be connected along
+// the paths, the straight-line distance is returned as an estimate.
+func segmentMeters(paths []Path, from, to Stop, fromSnaps, toSnaps []pathSnap) (meters float64, estimated bool) {
+	straight := haversineMeters(from.Lat, from.Lon, to.Lat, to.Lon)
+
+	best := math.Inf(1)
+	for i, path := range paths {
+		if fromSnaps[i].onPath() && toSnaps[i].onPath() {
+			best = min(best, alongPath(path.Points, fromSnaps[i].point, toSnaps[i].point))
+		}
+	}
+
+	if math.IsInf(best, 1) {
+		for i, fromPath := range paths {
+			if !fromSnaps[i].onPath() {
+				continue
+			}
+
+			for j, toPath := range paths {
+				if i == j || !toSnaps[j].onPath() {
+					continue
+				}
+
+				for _, fromEnd := range []int{0, len(fromPath.Points) - 1} {
+					for _, toEnd := range []int{0, len(toPath.Points) - 1} {
+						gap := haversineMeters(fromPath.Points[fromEnd].Lat, fromPath.Points[fromEnd].Lon,
+							toPath.Points[toEnd].Lat, toPath.Points[toEnd].Lon)
+						if gap > maxPathSnapMeters {
+							continue
+						}
+
+						best = min(best, alongPath(fromPath.Points, fromSnaps[i].point, fromEnd)+gap+
+							alongPath(toPath.Points, toEnd, toSnaps[j].point))
+					}
+				}
+			}
+		}
+	}
+
+	if math.IsInf(best, 1) {
+		return straight, true
+	}
+
+	// Snapping both stops to the path can cut a corner off a short segment
+	return max(best, straight), false
+}
@@ -0,0 +1,282 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetRouteStopSequence(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	sequence, err := client.GetRouteStopSequence(context.Background(), "J", "inbound")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if sequence.DirectionID != "J_IB" || sequence.DirectionName != "Inbound to Embarcadero" {
+		t.Errorf("Expected direction J_IB (Inbound to Embarcadero), got %s (%s)", sequence.DirectionID, sequence.DirectionName)
+	}
+
+	if len(sequence.Stops) != 2 {
+		t.Fatalf("Expected 2 stops, got %d", len(sequence.Stops))
+	}
+
+	first, last := sequence.Stops[0], sequence.Stops[1]
+	if first.ID != "5678" || first.Name != "Church St & Duboce Ave" || first.Code != "15678" {
+		t.Errorf("Expected first stop 5678 Church St & Duboce Ave, got %+v", first)
+	}
+
+	if !first.IsFirst || first.IsLast || last.IsFirst || !last.IsLast {
+		t.Errorf("Expected terminals to be flagged, got first %+v and last %+v", first, last)
+	}
+
+	if first.Sequence != 1 || last.Sequence != 2 {
+		t.Errorf("Expected sequence numbers 1 and 2, got %d and %d", first.Sequence, last.Sequence)
+	}
+
+	if first.DistanceMeters != 0 || last.DistanceMeters != 1218 {
+		t.Errorf("Expected distances 0 and 1218, got %d and %d", first.DistanceMeters, last.DistanceMeters)
+	}
+}
+
+func TestGetRouteStopSequenceErrors(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	if _, err := client.GetRouteStopSequence(context.Background(), "", "J_IB"); err != ErrRouteIDRequired {
+		t.Errorf("Expected ErrRouteIDRequired, got %v", err)
+	}
+
+	if _, err := client.GetRouteStopSequence(context.Background(), "J", ""); err != ErrDirectionRequired {
+		t.Errorf("Expected ErrDirectionRequired, got %v", err)
+	}
+
+	if _, err := client.GetRouteStopSequence(context.Background(), "J", "outbound"); !errors.Is(err, ErrDirectionNotFound) {
+		t.Errorf("Expected ErrDirectionNotFound, got %v", err)
+	}
+}
+
+func TestBuildStopSequenceFollowsPath(t *testing.T) {
+	details := &RouteDetails{
+		ID:    "L",
+		Title: "L Taraval",
+		Stops: []Stop{
+			{ID: "1", Name: "Start", Lat: 37.77, Lon: -122.43},
+			{ID: "2", Name: "Corner", Lat: 37.78, Lon: -122.43},
+			{ID: "3", Name: "End", Lat: 37.78, Lon: -122.42},
+		},
+		Paths: []Path{
+			{
+				ID: "1",
+				Points: []PathPoint{
+					{Lat: 37.77, Lon: -122.43},
+					{Lat: 37.78, Lon: -122.43},
+					{Lat: 37.78, Lon: -122.42},
+				},
+			},
+		},
+	}
+
+	// Skipping the corner stop must still measure around the corner
+	sequence := buildStopSequence(details, Direction{ID: "OB", Name: "Outbound", Stops: []string{"1", "missing", "3"}})
+
+	if len(sequence.Stops) != 2 {
+		t.Fatalf("Expected 2 stops, got %d", len(sequence.Stops))
+	}
+
+	if sequence.Stops[1].ID != "3" || sequence.Stops[1].Sequence != 2 {
+		t.Errorf("Expected stop 3 second, got %+v", sequence.Stops[1])
+	}
+
+	if distance := sequence.Stops[1].DistanceMeters; distance != 1991 {
+		t.Errorf("Expected 1991 meters along the path, got %d", distance)
+	}
+
+	// Without a path, distances fall back to straight lines
+	details.Paths = nil
+	sequence = buildStopSequence(details, Direction{ID: "OB", Name: "Outbound", Stops: []string{"1", "3"}})
+
+	if distance := sequence.Stops[1].DistanceMeters; distance != 1417 {
+		t.Errorf("Expected 1417 meters in a straight line, got %d", distance)
+	}
+
+	if !sequence.Stops[1].DistanceEstimated {
+		t.Error("Expected a straight-line distance to be flagged as estimated")
+	}
+}
+
+func TestBuildStopSequenceAcrossPaths(t *testing.T) {
+	stops := []Stop{
+		{ID: "1", Name: "Start", Lat: 37.7702, Lon: -122.4298},
+		{ID: "3", Name: "End", Lat: 37.78, Lon: -122.42},
+	}
+	direction := Direction{ID: "IB", Name: "Inbound", Stops: []string{"1", "3"}}
+
+	tests := []struct {
+		name      string
+		paths     []Path
+		estimated bool
+	}{
+		{
+			// The start is closer to the outbound path, the end to the inbound one
+			name: "separate path per direction",
+			paths: []Path{
+				{ID: "IB", Points: []PathPoint{{Lat: 37.77, Lon: -122.43}, {Lat: 37.78, Lon: -122.43}, {Lat: 37.78, Lon: -122.42}}},
+				{ID: "OB", Points: []PathPoint{{Lat: 37.7803, Lon: -122.4197}, {Lat: 37.7803, Lon: -122.4297}, {Lat: 37.7703, Lon: -122.4297}}},
+			},
+		},
+		{
+			name: "line split into paths",
+			paths: []Path{
+				{ID: "1", Points: []PathPoint{{Lat: 37.77, Lon: -122.43}, {Lat: 37.78, Lon: -122.43}}},
+				{ID: "2", Points: []PathPoint{{Lat: 37.7801, Lon: -122.43}, {Lat: 37.78, Lon: -122.42}}},
+			},
+		},
+		{
+			name: "disconnected paths",
+			paths: []Path{
+				{ID: "1", Points: []PathPoint{{Lat: 37.77, Lon: -122.43}, {Lat: 37.775, Lon: -122.43}}},
+				{ID: "2", Points: []PathPoint{{Lat: 37.78, Lon: -122.425}, {Lat: 37.78, Lon: -122.42}}},
+			},
+			estimated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := &RouteDetails{ID: "L", Stops: stops, Paths: tt.paths, Directions: []Direction{direction}}
+
+			sequence := buildStopSequence(details, direction)
+			end := sequence.Stops[1]
+
+			if end.DistanceEstimated != tt.estimated {
+				t.Errorf("Expected estimated to be %v, got %v", tt.estimated, end.DistanceEstimated)
+			}
+
+			// Around the corner is about 1990 meters, straight across about 1400
+			if !tt.estimated && (end.DistanceMeters < 1980 || end.DistanceMeters > 2010) {
+				t.Errorf("Expected about 1990 meters along the paths, got %d", end.DistanceMeters)
+			}
+
+			if tt.estimated && (end.DistanceMeters < 1380 || end.DistanceMeters > 1420) {
+				t.Errorf("Expected about 1400 meters in a straight line, got %d", end.DistanceMeters)
+			}
+
+			ride, err := stopsBetween(details, "1", "3")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if ride.DistanceEstimated != tt.estimated {
+				t.Errorf("Expected the ride's estimated flag to be %v, got %v", tt.estimated, ride.DistanceEstimated)
+			}
+		})
+	}
+}
+
+func TestGetStopsBetween(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	ride, err := client.GetStopsBetween(context.Background(), "J", "5678", "1234")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if ride.DirectionID != "J_IB" {
+		t.Errorf("Expected direction J_IB, got %s", ride.DirectionID)
+	}
+
+	if ride.From.ID != "5678" || ride.To.ID != "1234" {
+		t.Errorf("Expected a ride from 5678 to 1234, got %s to %s", ride.From.ID, ride.To.ID)
+	}
+
+	if len(ride.Stops) != 0 || ride.StopCount != 1 {
+		t.Errorf("Expected no stops in between and a count of 1, got %d and %d", len(ride.Stops), ride.StopCount)
+	}
+
+	if ride.DistanceMeters != 1218 {
+		t.Errorf("Expected 1218 meters, got %d", ride.DistanceMeters)
+	}
+
+	// J only runs inbound, from 5678 to 1234
+	_, err = client.GetStopsBetween(context.Background(), "J", "1234", "5678")
+	if !errors.Is(err, ErrStopsNotInSameDirection) {
+		t.Errorf("Expected ErrStopsNotInSameDirection, got %v", err)
+	}
+
+	if _, err := client.GetStopsBetween(context.Background(), "J", "1234", "1234"); err != ErrSameStop {
+		t.Errorf("Expected ErrSameStop, got %v", err)
+	}
+
+	if _, err := client.GetStopsBetween(context.Background(), "J", "", "1234"); err != ErrStopIDRequired {
+		t.Errorf("Expected ErrStopIDRequired, got %v", err)
+	}
+}
+
+func TestStopsBetweenPicksDirection(t *testing.T) {
+	details := &RouteDetails{
+		ID: "K",
+		Stops: []Stop{
+			{ID: "1", Name: "Balboa Park", Lat: 37.720, Lon: -122.447},
+			{ID: "2", Name: "Forest Hill", Lat: 37.748, Lon: -122.459},
+			{ID: "3", Name: "Castro", Lat: 37.762, Lon: -122.435},
+			{ID: "4", Name: "Church", Lat: 37.767, Lon: -122.429},
+			{ID: "5", Name: "Embarcadero", Lat: 37.793, Lon: -122.397},
+			{ID: "6", Name: "Shuttle Stop", Lat: 37.700, Lon: -122.400},
+		},
+		Directions: []Direction{
+			{ID: "K_IB", Name: "Inbound to Embarcadero", UseForUI: true, Stops: []string{"1", "2", "3", "4", "5"}},
+			{ID: "K_OB", Name: "Outbound to Balboa Park", UseForUI: true, Stops: []string{"5", "4", "3", "2", "1"}},
+			{ID: "K_SHUTTLE", Name: "Shuttle", Stops: []string{"6"}},
+		},
+	}
+
+	ride, err := stopsBetween(details, "3", "5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if ride.DirectionID != "K_IB" || ride.StopCount != 2 || len(ride.Stops) != 1 || ride.Stops[0].ID != "4" {
+		t.Errorf("Expected inbound past Church, got %+v", ride)
+	}
+
+	ride, err = stopsBetween(details, "4", "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if ride.DirectionID != "K_OB" || ride.StopCount != 3 {
+		t.Errorf("Expected outbound 3 stops, got %s %d", ride.DirectionID, ride.StopCount)
+	}
+
+	if ride.DistanceMeters != ride.To.DistanceMeters-ride.From.DistanceMeters || ride.DistanceMeters <= 0 {
+		t.Errorf("Expected a positive distance between the stops, got %d", ride.DistanceMeters)
+	}
+
+	_, err = stopsBetween(details, "3", "6")
+	if !errors.Is(err, ErrStopsNotInSameDirection) {
+		t.Fatalf("Expected ErrStopsNotInSameDirection, got %v", err)
+	}
+
+	expected := "stops are not on the same direction of the route: route K doesn't run from stop 3 " +
+		"(Inbound to Embarcadero, Outbound to Balboa Park) to stop 6 (Shuttle)"
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err.Error())
+	}
+
+	if _, err := stopsBetween(details, "3", "99"); !errors.Is(err, ErrStopNotOnRoute) {
+		t.Errorf("Expected ErrStopNotOnRoute, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// flightCall is an in-flight or completed call shared by concurrent callers
+type flightCall struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+}
+
+// flightGroup coalesces concurrent calls with the same key into a single
+// execution whose result is shared by every caller
+type flightGroup struct {
+	mutex sync.Mutex
+	calls map[string]*flightCall
+}
+
+// newFlightGroup creates an empty flight group
+func newFlightGroup() *flightGroup {
+	return &flightGroup{
+		calls: make(map[string]*flightCall),
+	}
+}
+
+// do executes fn once for all concurrent callers using the same key. The
+// first caller runs fn with its own context; the others wait for the shared
+// result or for their own context to be done. If the shared call fails only
+// because the first caller's context was cancelled, waiting callers whose
+// contexts are still live try again rather than inheriting the cancellation.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	for {
+		g.mutex.Lock()
+		if call, ok := g.calls[key]; ok {
+			g.mutex.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-call.done:
+			}
+
+			if isContextError(call.err) && ctx.Err() == nil {
+				continue
+			}
+
+			return call.val, call.err
+		}
+
+		call := &flightCall{done: make(chan struct{})}
+		g.calls[key] = call
+		g.mutex.Unlock()
+
+		g.run(key, call, fn)
+
+		return call.val, call.err
+	}
+}
+
+// run executes fn for the call and releases any waiting callers, even if fn panics
+func (g *flightGroup) run(key string, call *flightCall, fn func() (interface{}, error)) {
+	defer func() {
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		close(call.done)
+	}()
+
+	call.val, call.err = fn()
+}
+
+// isContextError reports whether err was caused by a cancelled or expired context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,173 @@
+package muni
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowServer returns the given response after a delay, counting requests
+func slowServer(response string, delay time.Duration, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(response))
+	}))
+}
+
+func TestConcurrentRouteDetailsShareRequest(t *testing.T) {
+	var calls int32
+	server := slowServer(mockRouteDetailsResponse, 50*time.Millisecond, &calls)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			details, err := client.GetRouteDetails(context.Background(), "N")
+			if err == nil && details.ID != "N" {
+				t.Errorf("Expected route ID to be N, got %s", details.ID)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", got)
+	}
+}
+
+func TestConcurrentPredictionsShareRequest(t *testing.T) {
+	var calls int32
+	server := slowServer(mockPredictionsResponse, 50*time.Millisecond, &calls)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetPredictions(context.Background(), "N", "1234"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", got)
+	}
+}
+
+func TestFlightGroupWaiterSurvivesLeaderCancellation(t *testing.T) {
+	group := newFlightGroup()
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	started := make(chan struct{})
+
+	go func() {
+		_, _ = group.do(leaderCtx, "key", func() (interface{}, error) {
+			close(started)
+			<-leaderCtx.Done()
+			return nil, leaderCtx.Err()
+		})
+	}()
+
+	<-started
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancelLeader()
+	}()
+
+	val, err := group.do(context.Background(), "key", func() (interface{}, error) {
+		return "fresh", nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if val != "fresh" {
+		t.Errorf("Expected waiter to retry the call, got %v", val)
+	}
+}
+
+func TestConcurrentRouteDetailsGetOwnCopies(t *testing.T) {
+	var calls int32
+	server := slowServer(mockRouteDetailsResponse, 50*time.Millisecond, &calls)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	defer client.Close()
+
+	const callers = 4
+	results := make([]*RouteDetails, callers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			details, err := client.GetRouteDetails(context.Background(), "N")
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			// Each caller changes what it got back
+			details.Alerts = append(details.Alerts, Alert{ID: fmt.Sprint(i)})
+			details.Stops[0].Name = fmt.Sprint("caller ", i)
+			results[i] = details
+		}(i)
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", got)
+	}
+
+	for i, details := range results {
+		if details == nil {
+			continue
+		}
+
+		if want := fmt.Sprint("caller ", i); details.Stops[0].Name != want {
+			t.Errorf("Expected caller %d to see its own stop name %q, got %q", i, want, details.Stops[0].Name)
+		}
+
+		if len(details.Alerts) != 1 {
+			t.Errorf("Expected caller %d to see only its own alert, got %d", i, len(details.Alerts))
+		}
+	}
+
+	// The cached details are unaffected
+	details, err := client.GetRouteDetails(context.Background(), "N")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if details.Stops[0].Name != "Ocean Beach" {
+		t.Errorf("Expected cached stop name to be Ocean Beach, got %q", details.Stops[0].Name)
+	}
+}
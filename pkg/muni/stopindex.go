@@ -0,0 +1,321 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Stop search limits
+const (
+	defaultStopSearchLimit = 10
+	maxStopSearchLimit     = 50
+)
+
+// Stop lookup errors
+var (
+	ErrQueryRequired    = errors.New("search query is required")
+	ErrStopCodeRequired = errors.New("stop code is required")
+	ErrStopCodeNotFound = errors.New("unknown stop code")
+)
+
+// StopDirection is a direction of travel on a route that serves a stop
+type StopDirection struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// StopRoute is a route serving a stop, with the directions that stop there
+type StopRoute struct {
+	RouteID        string          `json:"route_id"`
+	RouteTitle     string          `json:"route_title"`
+	RouteColor     string          `json:"route_color,omitempty"`
+	RouteTextColor string          `json:"route_text_color,omitempty"`
+	Directions     []StopDirection `json:"directions,omitempty"`
+}
+
+// StopInfo describes a stop across every route that serves it
+type StopInfo struct {
+	ID     string      `json:"id"`
+	Code   string      `json:"code,omitempty"`
+	Name   string      `json:"name"`
+	Lat    float64     `json:"lat"`
+	Lon    float64     `json:"lon"`
+	Routes []StopRoute `json:"routes"`
+}
+
+// clone returns a copy of the stop that can be changed without affecting the index
+func (s *StopInfo) clone() *StopInfo {
+	info := *s
+	info.Routes = slices.Clone(s.Routes)
+	for i := range info.Routes {
+		info.Routes[i].Directions = slices.Clone(info.Routes[i].Directions)
+	}
+	return &info
+}
+
+// StopMatch is a stop found by a search, with how well it matched from 0 to 1
+type StopMatch struct {
+	StopInfo
+	Score float64 `json:"score"`
+}
+
+// stopIndex holds every stop in an agency's system, along with the routes
+// serving each stop. It records the revision of every route it was built
+// from so it can be rebuilt when routes change.
+type stopIndex struct {
+	stops     []*StopInfo
+	byID      map[string]*StopInfo
+	byCode    map[string]*StopInfo
+	tokens    map[string][]string
+	grid      *stopGrid
+	revisions map[string]int
+}
+
+// newStopIndex builds a stop index from the details of every route
+func newStopIndex(routes []*RouteDetails, revisions map[string]int) *stopIndex {
+	index := &stopIndex{
+		byID:      make(map[string]*StopInfo),
+		byCode:    make(map[string]*StopInfo),
+		tokens:    make(map[string][]string),
+		revisions: revisions,
+	}
+
+	for _, route := range routes {
+		// Directions that stop at each stop on this route
+		directions := make(map[string][]StopDirection)
+		for _, direction := range route.Directions {
+			for _, stopID := range direction.Stops {
+				directions[stopID] = append(directions[stopID], StopDirection{ID: direction.ID, Name: direction.Name})
+			}
+		}
+
+		for _, stop := range route.Stops {
+			if stop.Hidden {
+				continue
+			}
+
+			info, ok := index.byID[stop.ID]
+			if !ok {
+				info = &StopInfo{
+					ID:   stop.ID,
+					Code: stop.Code,
+					Name: stop.Name,
+					Lat:  stop.Lat,
+					Lon:  stop.Lon,
+				}
+				index.byID[stop.ID] = info
+				if stop.Code != "" {
+					index.byCode[stop.Code] = info
+				}
+				index.tokens[stop.ID] = normalizeTokens(stop.Name)
+				index.stops = append(index.stops, info)
+			}
+
+			info.Routes = append(info.Routes, StopRoute{
+				RouteID:        route.ID,
+				RouteTitle:     route.Title,
+				RouteColor:     route.Color,
+				RouteTextColor: route.TextColor,
+				Directions:     directions[stop.ID],
+			})
+		}
+	}
+
+	index.grid = newStopGrid(index.stops)
+
+	return index
+}
+
+// search returns up to limit stops whose names match query, best matches first
+func (x *stopIndex) search(query string, limit int) []StopMatch {
+	queryTokens := normalizeTokens(query)
+
+	matches := []StopMatch{}
+	for _, stop := range x.stops {
+		if score := matchScore(queryTokens, x.tokens[stop.ID]); score >= minMatchScore {
+			matches = append(matches, StopMatch{StopInfo: *stop.clone(), Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Name < matches[j].Name
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches
+}
+
+// stopIndexes caches a stop index per agency
+type stopIndexes struct {
+	indexes map[string]*stopIndex
+	mutex   sync.Mutex
+}
+
+// newStopIndexes creates an empty stop index cache
+func newStopIndexes() *stopIndexes {
+	return &stopIndexes{
+		indexes: make(map[string]*stopIndex),
+	}
+}
+
+// clear drops every stop index so they are rebuilt on next use
+func (s *stopIndexes) clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.indexes = make(map[string]*stopIndex)
+}
+
+// routeRevisions returns the revision of every visible route
+func routeRevisions(routes []RouteInfo) map[string]int {
+	revisions := make(map[string]int, len(routes))
+	for _, route := range routes {
+		if !route.Hidden {
+			revisions[route.ID] = route.Rev
+		}
+	}
+	return revisions
+}
+
+// stopIndex returns the stop index for the request's agency, building it
+// from every route's details when it is missing or when routes have been
+// added, removed or revised since it was built
+func (c *Client) stopIndex(ctx context.Context) (*stopIndex, error) {
+	agency := c.agencyFor(ctx)
+
+	routes, err := c.GetAllRoutes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	revisions := routeRevisions(routes)
+
+	c.stops.mutex.Lock()
+	index := c.stops.indexes[agency]
+	c.stops.mutex.Unlock()
+
+	if index != nil && maps.Equal(index.revisions, revisions) {
+		return index, nil
+	}
+
+	result, err := c.flights.do(ctx, "stop_index:"+agency, func() (interface{}, error) {
+		details, err := c.allRouteDetails(ctx, routes)
+		if err != nil {
+			return nil, err
+		}
+
+		index := newStopIndex(details, revisions)
+
+		c.stops.mutex.Lock()
+		c.stops.indexes[agency] = index
+		c.stops.mutex.Unlock()
+
+		return index, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*stopIndex), nil
+}
+
+// GetStopDetails returns a stop's name, code and location along with every
+// route serving it, the directions that stop there, and the routes' colors
+func (c *Client) GetStopDetails(ctx context.Context, stopID string) (*StopInfo, error) {
+	if stopID == "" {
+		return nil, ErrStopIDRequired
+	}
+
+	index, err := c.stopIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stop, ok := index.byID[stopID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrStopNotFound, stopID)
+	}
+
+	return stop.clone(), nil
+}
+
+// SearchStops finds stops whose names fuzzily match query, ignoring case,
+// punctuation and spelled out street words, so "church and duboce" finds
+// "Church St & Duboce Ave". At most limit matches are returned, best first;
+// a limit of zero or less returns the default of 10.
+func (c *Client) SearchStops(ctx context.Context, query string, limit int) ([]StopMatch, error) {
+	if len(normalizeTokens(query)) == 0 {
+		return nil, ErrQueryRequired
+	}
+
+	if limit <= 0 {
+		limit = defaultStopSearchLimit
+	}
+	limit = min(limit, maxStopSearchLimit)
+
+	index, err := c.stopIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return index.search(query, limit), nil
+}
+
+// FindNearbyStops finds stops within radiusMeters of a location, closest
+// first, with the great-circle distance and estimated walking time to each.
+// At most limit stops are returned. A radius of zero or less searches the
+// default of 500 meters, and a limit of zero or less returns the default of 10.
+func (c *Client) FindNearbyStops(ctx context.Context, lat, lon, radiusMeters float64, limit int) ([]NearbyStop, error) {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return nil, ErrInvalidCoordinates
+	}
+
+	if radiusMeters <= 0 {
+		radiusMeters = defaultNearbyRadiusMeters
+	}
+	radiusMeters = min(radiusMeters, maxNearbyRadiusMeters)
+
+	if limit <= 0 {
+		limit = defaultNearbyLimit
+	}
+	limit = min(limit, maxNearbyLimit)
+
+	index, err := c.stopIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return index.grid.nearby(lat, lon, radiusMeters, limit), nil
+}
+
+// ResolveStopCode looks up a stop by the public stop code printed on stop
+// signs, e.g. "13860", which differs from the stop ID used by the API
+func (c *Client) ResolveStopCode(ctx context.Context, code string) (*StopInfo, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, ErrStopCodeRequired
+	}
+
+	index, err := c.stopIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stop, ok := index.byCode[code]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrStopCodeNotFound, code)
+	}
+
+	return stop.clone(), nil
+}
@@ -0,0 +1,242 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSearchStops(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	queries := []string{
+		"church and duboce",
+		"Church & Duboce St",
+		"CHURCH ST AND DUBOCE AVENUE",
+		"chruch duboce",
+		"church dub",
+	}
+
+	for _, query := range queries {
+		matches, err := client.SearchStops(context.Background(), query, 0)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", query, err)
+		}
+
+		if len(matches) == 0 || matches[0].ID != "5678" {
+			t.Errorf("Expected stop 5678 as the best match for %q, got %+v", query, matches)
+			continue
+		}
+
+		match := matches[0]
+		if match.Code != "15678" {
+			t.Errorf("Expected stop code 15678, got %s", match.Code)
+		}
+
+		if len(match.Routes) != 1 || match.Routes[0].RouteID != "J" {
+			t.Errorf("Expected stop 5678 to be served by J, got %+v", match.Routes)
+		}
+
+		if len(match.Routes[0].Directions) != 1 || match.Routes[0].Directions[0].ID != "J_IB" {
+			t.Errorf("Expected stop 5678 to be served inbound, got %+v", match.Routes[0].Directions)
+		}
+	}
+
+	// Stops served by several routes list each of them
+	matches, err := client.SearchStops(context.Background(), "ocean beach", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(matches) != 1 || len(matches[0].Routes) != 2 {
+		t.Errorf("Expected Ocean Beach to be served by 2 routes, got %+v", matches)
+	}
+
+	// Unrelated queries match nothing
+	matches, err = client.SearchStops(context.Background(), "golden gate park", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(matches) != 0 {
+		t.Errorf("Expected no matches, got %+v", matches)
+	}
+
+	// Test with an empty query
+	if _, err := client.SearchStops(context.Background(), " & ", 0); err != ErrQueryRequired {
+		t.Errorf("Expected ErrQueryRequired, got %v", err)
+	}
+}
+
+func TestSearchStopsResultsDontShareIndex(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	matches, err := client.SearchStops(context.Background(), "church and duboce", 0)
+	if err != nil || len(matches) == 0 {
+		t.Fatalf("Expected matches, got %v (%v)", matches, err)
+	}
+
+	// Changing a result must not change the index
+	matches[0].Routes[0].RouteID = "changed"
+	matches[0].Routes[0].Directions[0].Name = "changed"
+
+	matches, err = client.SearchStops(context.Background(), "church and duboce", 0)
+	if err != nil || len(matches) == 0 {
+		t.Fatalf("Expected matches, got %v (%v)", matches, err)
+	}
+
+	route := matches[0].Routes[0]
+	if route.RouteID == "changed" || route.Directions[0].Name == "changed" {
+		t.Errorf("Expected the index to be unaffected by changes to results, got %+v", route)
+	}
+}
+
+func TestResolveStopCode(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	stop, err := client.ResolveStopCode(context.Background(), " 15678 ")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if stop.ID != "5678" || stop.Name != "Church St & Duboce Ave" {
+		t.Errorf("Expected stop 5678 Church St & Duboce Ave, got %s %s", stop.ID, stop.Name)
+	}
+
+	// Stop IDs are not stop codes
+	if _, err := client.ResolveStopCode(context.Background(), "5678"); !errors.Is(err, ErrStopCodeNotFound) {
+		t.Errorf("Expected ErrStopCodeNotFound, got %v", err)
+	}
+
+	// Test with an empty code
+	if _, err := client.ResolveStopCode(context.Background(), ""); err != ErrStopCodeRequired {
+		t.Errorf("Expected ErrStopCodeRequired, got %v", err)
+	}
+}
+
+func TestGetStopDetails(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	stop, err := client.GetStopDetails(context.Background(), "1234")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if stop.Name != "Ocean Beach" || stop.Lat != 37.7749 || stop.Lon != -122.4194 {
+		t.Errorf("Expected Ocean Beach at 37.7749,-122.4194, got %+v", stop)
+	}
+
+	if len(stop.Routes) != 2 {
+		t.Fatalf("Expected 2 routes serving stop 1234, got %+v", stop.Routes)
+	}
+
+	n, j := stop.Routes[0], stop.Routes[1]
+	if n.RouteID != "N" || n.RouteColor != "003399" || n.RouteTextColor != "FFFFFF" {
+		t.Errorf("Expected N with its colors, got %+v", n)
+	}
+
+	if j.RouteID != "J" || j.RouteColor != "339900" {
+		t.Errorf("Expected J with its colors, got %+v", j)
+	}
+
+	if len(j.Directions) != 1 || j.Directions[0].ID != "J_IB" || j.Directions[0].Name != "Inbound to Embarcadero" {
+		t.Errorf("Expected J to stop inbound, got %+v", j.Directions)
+	}
+
+	// Test with an unknown stop
+	if _, err := client.GetStopDetails(context.Background(), "9999"); !errors.Is(err, ErrStopNotFound) {
+		t.Errorf("Expected ErrStopNotFound, got %v", err)
+	}
+
+	// Test with an empty stop ID
+	if _, err := client.GetStopDetails(context.Background(), ""); err != ErrStopIDRequired {
+		t.Errorf("Expected ErrStopIDRequired, got %v", err)
+	}
+}
+
+func TestStopIndexRebuildsOnRevisionChange(t *testing.T) {
+	var routes atomic.Value
+	routes.Store(mockRoutesResponse)
+	var detailCalls int32
+
+	responses := mockSystemResponses()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/routes"):
+			_, _ = w.Write([]byte(routes.Load().(string)))
+		case responses[r.URL.Path] != "":
+			atomic.AddInt32(&detailCalls, 1)
+			_, _ = w.Write([]byte(responses[r.URL.Path]))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, WithoutCache(), WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	if _, err := client.GetStopDetails(context.Background(), "5678"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The index is reused while route revisions are unchanged
+	if _, err := client.GetStopDetails(context.Background(), "5678"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&detailCalls); got != 2 {
+		t.Errorf("Expected route details to be fetched once per route, got %d requests", got)
+	}
+
+	// A new revision of a route rebuilds the index
+	routes.Store(strings.Replace(mockRoutesResponse, `"rev": 1`, `"rev": 2`, 1))
+
+	if _, err := client.GetStopDetails(context.Background(), "5678"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&detailCalls); got != 4 {
+		t.Errorf("Expected route details to be fetched again after a revision, got %d requests", got)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"church", "church", 0},
+		{"church", "chruch", 1},
+		{"duboce", "duboc", 1},
+		{"judah", "judha", 1},
+		{"castro", "market", 5},
+	}
+
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.expected {
+			t.Errorf("Expected distance between %q and %q to be %d, got %d", test.a, test.b, test.expected, got)
+		}
+	}
+}
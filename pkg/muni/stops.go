@@ -0,0 +1,136 @@
+package muni
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+)
+
+// routeDetailsConcurrency bounds concurrent requests when fetching details for every route
+const routeDetailsConcurrency = 8
+
+// allRouteDetails fetches the details of every visible route, using the cache
+// where possible. It fails if the details of any route can't be fetched.
+func (c *Client) allRouteDetails(ctx context.Context, routes []RouteInfo) ([]*RouteDetails, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	details := make([]*RouteDetails, len(routes))
+	errs := make([]error, len(routes))
+	sem := make(chan struct{}, routeDetailsConcurrency)
+
+	var wg sync.WaitGroup
+	for i, route := range routes {
+		if route.Hidden {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, routeID string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+
+			details[i], errs[i] = c.GetRouteDetails(ctx, routeID)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i, route.ID)
+	}
+	wg.Wait()
+
+	result := make([]*RouteDetails, 0, len(details))
+	for i, detail := range details {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if detail != nil {
+			result = append(result, detail)
+		}
+	}
+
+	return result, nil
+}
+
+// GetStopPredictions fetches predictions for every route serving a stop,
+// merged into a single list sorted by arrival time. Each prediction carries
+// the ID, title and color of its route. Routes whose predictions can't be
+// fetched are left out unless every route fails.
+func (c *Client) GetStopPredictions(ctx context.Context, stopID string) ([]Prediction, error) {
+	if stopID == "" {
+		return nil, ErrStopIDRequired
+	}
+
+	stop, err := c.GetStopDetails(ctx, stopID)
+	if err != nil {
+		return nil, err
+	}
+	routes := stop.Routes
+
+	results := make([][]Prediction, len(routes))
+	errs := make([]error, len(routes))
+
+	var wg sync.WaitGroup
+	for i, route := range routes {
+		wg.Add(1)
+		go func(i int, route StopRoute) {
+			defer wg.Done()
+
+			predictions, err := c.GetPredictions(ctx, route.RouteID, stopID)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			// Fill in route metadata the prediction response didn't include
+			for j := range predictions {
+				if predictions[j].RouteID == "" {
+					predictions[j].RouteID = route.RouteID
+				}
+				if predictions[j].RouteTitle == "" {
+					predictions[j].RouteTitle = route.RouteTitle
+				}
+				if predictions[j].RouteColor == "" {
+					predictions[j].RouteColor = route.RouteColor
+				}
+			}
+			results[i] = predictions
+		}(i, route)
+	}
+	wg.Wait()
+
+	merged := []Prediction{}
+	var firstErr error
+	failed := 0
+	for i := range routes {
+		if errs[i] != nil {
+			log.Printf("Error fetching predictions for route %s at stop %s: %v", routes[i].RouteID, stopID, errs[i])
+			failed++
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		merged = append(merged, results[i]...)
+	}
+
+	if failed == len(routes) {
+		return nil, firstErr
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].SecondsUntil != merged[j].SecondsUntil {
+			return merged[i].SecondsUntil < merged[j].SecondsUntil
+		}
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+
+	return merged, nil
+}
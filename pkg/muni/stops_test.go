@@ -0,0 +1,107 @@
+package muni
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGetStopPredictions(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	defer client.Close()
+
+	predictions, err := client.GetStopPredictions(context.Background(), "1234")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(predictions) != 3 {
+		t.Fatalf("Expected 3 predictions across both routes, got %d", len(predictions))
+	}
+
+	expected := []struct {
+		routeID string
+		minutes int
+	}{
+		{"J", 2},
+		{"N", 5},
+		{"J", 12},
+	}
+
+	for i, want := range expected {
+		if predictions[i].RouteID != want.routeID || predictions[i].Minutes != want.minutes {
+			t.Errorf("Expected prediction %d to be route %s in %d minutes, got route %s in %d minutes",
+				i, want.routeID, want.minutes, predictions[i].RouteID, predictions[i].Minutes)
+		}
+	}
+
+	if predictions[0].RouteColor != "339900" {
+		t.Errorf("Expected route color 339900, got %s", predictions[0].RouteColor)
+	}
+
+	// Stop served by a single route
+	predictions, err = client.GetStopPredictions(context.Background(), "5678")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, prediction := range predictions {
+		if prediction.RouteID != "J" {
+			t.Errorf("Expected only route J at stop 5678, got %s", prediction.RouteID)
+		}
+	}
+
+	// Unknown stop
+	_, err = client.GetStopPredictions(context.Background(), "9999")
+	if !errors.Is(err, ErrStopNotFound) {
+		t.Errorf("Expected ErrStopNotFound, got %v", err)
+	}
+
+	// Empty stop ID
+	_, err = client.GetStopPredictions(context.Background(), "")
+	if err != ErrStopIDRequired {
+		t.Errorf("Expected ErrStopIDRequired, got %v", err)
+	}
+}
+
+func TestGetStopPredictionsOrdersWithinMinute(t *testing.T) {
+	const prefix = "/v2.0/riders/agencies/sfmta-cis"
+
+	// Both routes arrive in the first minute, the J ten seconds before the N
+	responses := mockSystemResponses()
+	responses[prefix+"/nstops/N:1234/predictions"] = strings.Replace(mockPredictionsResponse,
+		`"timestamp": 1710936300000`, `"timestamp": 1710936110000`, 1)
+	responses[prefix+"/nstops/J:1234/predictions"] = strings.Replace(mockJPredictionsResponse,
+		`"timestamp": 1710936120000`, `"timestamp": 1710936100000`, 1)
+
+	server := mockAPIServer(responses)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	defer client.Close()
+
+	predictions, err := client.GetStopPredictions(context.Background(), "1234")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(predictions) != 3 {
+		t.Fatalf("Expected 3 predictions across both routes, got %d", len(predictions))
+	}
+
+	for i, routeID := range []string{"J", "N", "J"} {
+		if predictions[i].RouteID != routeID {
+			t.Errorf("Expected prediction %d to be route %s, got route %s in %d seconds",
+				i, routeID, predictions[i].RouteID, predictions[i].SecondsUntil)
+		}
+	}
+
+	if predictions[0].Minutes != predictions[1].Minutes {
+		t.Errorf("Expected the first two predictions in the same minute, got %d and %d",
+			predictions[0].Minutes, predictions[1].Minutes)
+	}
+}
@@ -0,0 +1,96 @@
+package muni
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// VehicleResponse represents a vehicle as reported by the vehicles API
+type VehicleResponse struct {
+	ID              string  `json:"id"`
+	Lat             float64 `json:"lat"`
+	Lon             float64 `json:"lon"`
+	Heading         int     `json:"heading"`
+	Speed           float64 `json:"speed"`
+	DirectionID     string  `json:"directionId"`
+	TripID          string  `json:"tripId"`
+	VehicleType     string  `json:"vehicleType"`
+	Predictable     bool    `json:"predictable"`
+	SecsSinceReport int     `json:"secsSinceReport"`
+	Timestamp       int64   `json:"timestamp"`
+}
+
+// VehicleLocation represents the last reported position of a vehicle on a
+// route. Heading is in degrees clockwise from north and speed is in km/h.
+// LastReportAt is on the local clock, derived from how long before the
+// response the vehicle last reported.
+type VehicleLocation struct {
+	ID                 string    `json:"id"`
+	RouteID            string    `json:"route_id"`
+	Lat                float64   `json:"lat"`
+	Lon                float64   `json:"lon"`
+	Heading            int       `json:"heading"`
+	SpeedKmh           float64   `json:"speed_kmh"`
+	DirectionID        string    `json:"direction_id,omitempty"`
+	TripID             string    `json:"trip_id,omitempty"`
+	VehicleType        string    `json:"vehicle_type,omitempty"`
+	Predictable        bool      `json:"predictable"`
+	LastReportAt       time.Time `json:"last_report_at"`
+	SecondsSinceReport int       `json:"seconds_since_report"`
+	FetchedAt          time.Time `json:"fetched_at"`
+}
+
+// GetVehicleLocations fetches the last reported position of every vehicle on
+// a route. Locations may be served from the cache if WithVehicleTTL is set.
+func (c *Client) GetVehicleLocations(ctx context.Context, routeID string) ([]VehicleLocation, error) {
+	if routeID == "" {
+		return nil, ErrRouteIDRequired
+	}
+
+	agency := c.agencyFor(ctx)
+	cacheKey := buildCacheKey(CacheKindVehicles, agency, routeID)
+
+	result, err := fetchCached(ctx, c, cacheKey, func(ctx context.Context) ([]VehicleResponse, error) {
+		url := fmt.Sprintf("%s/v2.0/riders/agencies/%s/routes/%s/vehicles", c.baseURL, agency, routeID)
+
+		var vehicles []VehicleResponse
+		if err := c.fetchJSON(ctx, EndpointVehicles, url, &vehicles); err != nil {
+			return nil, err
+		}
+
+		return vehicles, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return convertVehicles(routeID, result.value, result.fetchedAt, time.Now()), nil
+}
+
+// convertVehicles converts a vehicles response fetched at the given time to
+// vehicle locations as of now
+func convertVehicles(routeID string, vehicles []VehicleResponse, fetchedAt, now time.Time) []VehicleLocation {
+	age := int(now.Sub(fetchedAt).Seconds())
+
+	locations := make([]VehicleLocation, len(vehicles))
+	for i, vehicle := range vehicles {
+		locations[i] = VehicleLocation{
+			ID:                 vehicle.ID,
+			RouteID:            routeID,
+			Lat:                vehicle.Lat,
+			Lon:                vehicle.Lon,
+			Heading:            vehicle.Heading,
+			SpeedKmh:           vehicle.Speed,
+			DirectionID:        vehicle.DirectionID,
+			TripID:             vehicle.TripID,
+			VehicleType:        vehicle.VehicleType,
+			Predictable:        vehicle.Predictable,
+			LastReportAt:       fetchedAt.Add(-time.Duration(vehicle.SecsSinceReport) * time.Second),
+			SecondsSinceReport: vehicle.SecsSinceReport + age,
+			FetchedAt:          fetchedAt,
+		}
+	}
+
+	return locations
+}
@@ -0,0 +1,65 @@
+package muni
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetVehicleLocations(t *testing.T) {
+	server := mockAPIServer(mockSystemResponses())
+	defer server.Close()
+
+	client := NewClient(server.URL, WithRetryPolicy(NoRetry()))
+	defer client.Close()
+
+	vehicles, err := client.GetVehicleLocations(context.Background(), "N")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(vehicles) != 2 {
+		t.Fatalf("Expected 2 vehicles, got %d", len(vehicles))
+	}
+
+	vehicle := vehicles[0]
+	if vehicle.ID != "1440" || vehicle.RouteID != "N" {
+		t.Errorf("Expected vehicle 1440 on route N, got %s on %s", vehicle.ID, vehicle.RouteID)
+	}
+
+	if vehicle.Lat != 37.7601 || vehicle.Lon != -122.4687 {
+		t.Errorf("Expected position 37.7601,-122.4687, got %v,%v", vehicle.Lat, vehicle.Lon)
+	}
+
+	if vehicle.Heading != 90 || vehicle.SpeedKmh != 24.5 {
+		t.Errorf("Expected heading 90 at 24.5 km/h, got %d at %v", vehicle.Heading, vehicle.SpeedKmh)
+	}
+
+	if vehicle.DirectionID != "IB" {
+		t.Errorf("Expected direction IB, got %s", vehicle.DirectionID)
+	}
+
+	if vehicle.SecondsSinceReport != 10 {
+		t.Errorf("Expected 10 seconds since report, got %d", vehicle.SecondsSinceReport)
+	}
+
+	// Test with empty route ID
+	if _, err := client.GetVehicleLocations(context.Background(), ""); err != ErrRouteIDRequired {
+		t.Errorf("Expected ErrRouteIDRequired, got %v", err)
+	}
+}
+
+func TestConvertVehiclesAccountsForDataAge(t *testing.T) {
+	fetchedAt := time.Now()
+	vehicles := []VehicleResponse{{ID: "1440", SecsSinceReport: 10}}
+
+	locations := convertVehicles("N", vehicles, fetchedAt, fetchedAt.Add(time.Minute))
+
+	if got := locations[0].SecondsSinceReport; got != 70 {
+		t.Errorf("Expected 70 seconds since report, got %d", got)
+	}
+
+	if !locations[0].LastReportAt.Equal(fetchedAt.Add(-10 * time.Second)) {
+		t.Errorf("Expected last report 10 seconds before fetch, got %v", locations[0].LastReportAt)
+	}
+}